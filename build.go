@@ -0,0 +1,10 @@
+package framework
+
+// Build info of the service binary published in its registry metadata, set at link time:
+//
+//	go build -ldflags "-X github.com/ZYallers/rpcx-framework.GitCommit=$(git rev-parse --short HEAD) \
+//	  -X github.com/ZYallers/rpcx-framework.BuildTime=$(date +%Y-%m-%dT%H:%M:%S%z)"
+var (
+	GitCommit string
+	BuildTime string
+)
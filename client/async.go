@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/smallnest/rpcx/client"
+)
+
+// Future is the pending result of a call made by Go.
+type Future struct {
+	done  chan struct{}
+	reply *Reply
+	err   error
+}
+
+// Done is closed when the call is done.
+func (f *Future) Done() <-chan struct{} { return f.done }
+
+// Wait waits for the call and returns its result.
+func (f *Future) Wait() (*Reply, error) {
+	<-f.done
+	return f.reply, f.err
+}
+
+// Go calls serviceMethod of service like XClientCtx without waiting for the reply.
+func Go(ctx context.Context, service, serviceMethod string, args map[string]interface{}, opts ...CallOption) *Future {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		defer safe.Defer()
+		f.reply, f.err = XClientCtx(ctx, service, serviceMethod, args, opts...)
+	}()
+	return f
+}
+
+// Broadcast calls serviceMethod on every server of service like XClientCtx, it fails if any server fails and
+// replies with the reply of one of them.
+func Broadcast(ctx context.Context, service, serviceMethod string, args map[string]interface{}, opts ...CallOption) (*Reply, error) {
+	return invoke(ctx, service, serviceMethod, args, append(opts[:len(opts):len(opts)], withoutHedge()), client.XClient.Broadcast)
+}
+
+// Fork calls serviceMethod on every server of service like XClientCtx and replies with the first success.
+func Fork(ctx context.Context, service, serviceMethod string, args map[string]interface{}, opts ...CallOption) (*Reply, error) {
+	return invoke(ctx, service, serviceMethod, args, append(opts[:len(opts):len(opts)], withoutHedge()), client.XClient.Fork)
+}
+
+// BatchCall is a call of Batch.
+type BatchCall struct {
+	Service string
+	Method  string
+	Args    map[string]interface{}
+	Opts    []CallOption
+}
+
+// BatchResult is the result of a BatchCall.
+type BatchResult struct {
+	Reply *Reply
+	Err   error
+}
+
+// Batch makes the calls concurrently within the deadline of ctx and returns their results in the order of calls.
+func Batch(ctx context.Context, calls ...BatchCall) []BatchResult {
+	results := make([]BatchResult, len(calls))
+	var wg sync.WaitGroup
+	wg.Add(len(calls))
+	for i := range calls {
+		go func(i int) {
+			defer wg.Done()
+			defer safe.Defer()
+			c := calls[i]
+			results[i].Reply, results[i].Err = XClientCtx(ctx, c.Service, c.Method, c.Args, c.Opts...)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,383 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/helper/sender"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rcrowley/go-metrics"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/log"
+)
+
+// Breaker states, also the values of the state gauges.
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+const (
+	breakerBuckets            = 10
+	breakerDefaultWindow      = 10 * time.Second
+	breakerDefaultMinRequests = 20
+	breakerDefaultErrorRate   = 0.5
+	breakerDefaultSlowRate    = 0.5
+	breakerDefaultProbes      = 3
+	outlierDefaultFailures    = 5
+	outlierDefaultEjectTime   = 30 * time.Second
+	outlierDefaultMaxEject    = 50
+)
+
+var (
+	breakerStates = []string{"closed", "open", "half-open"}
+	healthDict    = safe.NewDict()
+)
+
+// guardPolicy is the resolved breaker and outlier policy of a call.
+type guardPolicy struct {
+	failures      int // 0 disables the breakers
+	openTime      time.Duration
+	window        time.Duration
+	minRequests   int
+	errorRate     float64
+	slowCall      time.Duration
+	slowRate      float64
+	probes        int
+	ejectFailures int // 0 disables the ejection
+	ejectTime     time.Duration
+	maxEject      int
+}
+
+func newGuardPolicy(p types.ClientProfile) *guardPolicy {
+	gp := &guardPolicy{failures: xClientBreakerFailures, openTime: xClientBreakerWindow, window: breakerDefaultWindow,
+		minRequests: breakerDefaultMinRequests, errorRate: breakerDefaultErrorRate, slowRate: breakerDefaultSlowRate,
+		probes: breakerDefaultProbes, ejectFailures: outlierDefaultFailures, ejectTime: outlierDefaultEjectTime,
+		maxEject: outlierDefaultMaxEject}
+	if p.BreakerFailures != nil {
+		gp.failures = *p.BreakerFailures
+	}
+	if p.BreakerWindow > 0 {
+		gp.openTime = time.Duration(p.BreakerWindow) * time.Millisecond
+	}
+	b, o := p.Breaker, p.Outlier
+	if b.Window > 0 {
+		gp.window = time.Duration(b.Window) * time.Millisecond
+	}
+	if b.MinRequests > 0 {
+		gp.minRequests = b.MinRequests
+	}
+	if b.ErrorRate > 0 {
+		gp.errorRate = b.ErrorRate
+	}
+	if b.SlowCall > 0 {
+		gp.slowCall = time.Duration(b.SlowCall) * time.Millisecond
+	}
+	if b.SlowRate > 0 {
+		gp.slowRate = b.SlowRate
+	}
+	if b.Probes > 0 {
+		gp.probes = b.Probes
+	}
+	if o.Failures != nil {
+		gp.ejectFailures = *o.Failures
+	}
+	if o.EjectTime > 0 {
+		gp.ejectTime = time.Duration(o.EjectTime) * time.Millisecond
+	}
+	if o.MaxEjectPercent > 0 {
+		gp.maxEject = o.MaxEjectPercent
+	}
+	return gp
+}
+
+// serviceHealth holds the breakers of the methods of the servers of a service and the ejections of its servers,
+// shared by its XClients.
+type serviceHealth struct {
+	service  string
+	mu       sync.Mutex
+	breakers map[string]*breaker // by server and method
+	nodes    map[string]*outlier // by server
+}
+
+type breaker struct {
+	state       int
+	changed     time.Time
+	consecutive int
+	buckets     [breakerBuckets]bucket
+	probing     int // half-open calls in flight
+	probed      int // half-open calls succeeded
+}
+
+type bucket struct {
+	start                     time.Time
+	requests, failures, slows int
+}
+
+type outlier struct {
+	consecutive  int
+	ejections    int // in a row
+	ejectedUntil time.Time
+}
+
+func health(service string) *serviceHealth {
+	if h, ok := healthDict.Get(service); ok {
+		return h.(*serviceHealth)
+	}
+	h := &serviceHealth{service: service, breakers: map[string]*breaker{}, nodes: map[string]*outlier{}}
+	if old, ok := healthDict.GetOrPut(service, h); ok {
+		return old.(*serviceHealth)
+	}
+	return h
+}
+
+// nodeGuard is the rpcx select plugin skipping the ejected servers and the ones whose breaker of the method is open.
+type nodeGuard struct {
+	health    *serviceHealth
+	policy    *guardPolicy
+	discovery client.ServiceDiscovery
+}
+
+// callNode is put in the context of an attempt to learn the server selected for it.
+type callNode struct {
+	mu       sync.Mutex
+	addr     string
+	guard    *nodeGuard
+	rejected bool   // every server selected was skipped
+	skips    int    // servers skipped so far
+	canary   bool   // goes to the canary servers
+	exclude  string // server of the request hedged
+}
+
+// selected returns the server selected, read by the hedge of the attempt.
+func (c *callNode) selected() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addr
+}
+
+// outcome returns the server selected and its guard, nil if none, and whether every server was skipped.
+func (c *callNode) outcome() (string, *nodeGuard, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addr, c.guard, c.rejected
+}
+
+// skipped returns the servers skipped so far by the selection.
+func (c *callNode) skipped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skips
+}
+
+func (c *callNode) skip(skips int, rejected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skips, c.rejected = skips, rejected
+}
+
+type callNodeKey struct{}
+
+func (g *nodeGuard) WrapSelect(next client.SelectFunc) client.SelectFunc {
+	return func(ctx context.Context, servicePath, serviceMethod string, args interface{}) string {
+		call, _ := ctx.Value(callNodeKey{}).(*callNode)
+		tries := 1
+		for i := 0; i < tries; i++ {
+			if call != nil {
+				call.skip(i, false)
+			}
+			addr := next(ctx, servicePath, serviceMethod, args)
+			if addr == "" {
+				return ""
+			}
+			if (call == nil || addr != call.exclude) && g.health.allow(addr, serviceMethod, g.policy, time.Now()) {
+				if call != nil {
+					call.mu.Lock()
+					call.addr, call.guard = addr, g
+					call.mu.Unlock()
+				}
+				return addr
+			}
+			if i == 0 {
+				// counted once a server is skipped, by the servers discovered, the ones never called included
+				tries = 2*len(g.discovery.GetServices()) + 1
+			}
+		}
+		if call != nil {
+			call.skip(tries, true)
+		}
+		return ""
+	}
+}
+
+// record counts the outcome of a call of method on addr, the errors of the handlers are successes.
+func (g *nodeGuard) record(addr, method string, err error, elapsed time.Duration) {
+	class := errorClass(err)
+	failed := err != nil && class != retryServer && class != ""
+	slow := !failed && g.policy.slowCall > 0 && elapsed >= g.policy.slowCall
+	var events []string
+	g.health.mu.Lock()
+	o, ok := g.health.nodes[addr]
+	if !ok {
+		o = &outlier{}
+		g.health.nodes[addr] = o
+	}
+	if g.policy.failures > 0 {
+		g.health.recordBreaker(addr, method, g.policy, class == "", failed, slow, &events)
+	}
+	if g.policy.ejectFailures > 0 && class != "" {
+		g.health.recordOutlier(addr, o, g.policy, failed, len(g.discovery.GetServices()), &events)
+	}
+	g.health.mu.Unlock()
+	g.health.report(events)
+}
+
+// allow reports whether addr may be called for method, it lets the probes of a half-open breaker through.
+func (h *serviceHealth) allow(addr, method string, gp *guardPolicy, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if o, ok := h.nodes[addr]; ok && gp.ejectFailures > 0 && now.Before(o.ejectedUntil) {
+		return false
+	}
+	b, ok := h.breakers[addr+method]
+	if !ok || gp.failures == 0 {
+		return true
+	}
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.changed) < gp.openTime {
+			return false
+		}
+		h.setState(addr, method, b, breakerHalfOpen, now)
+		fallthrough
+	case breakerHalfOpen:
+		// probes lost by the fail modes selecting several servers are given up after the open time
+		if b.probing >= gp.probes {
+			if now.Sub(b.changed) < gp.openTime {
+				return false
+			}
+			b.probing, b.changed = 0, now
+		}
+		b.probing++
+	}
+	return true
+}
+
+func (h *serviceHealth) recordBreaker(addr, method string, gp *guardPolicy, canceled, failed, slow bool, events *[]string) {
+	now := time.Now()
+	b, ok := h.breakers[addr+method]
+	if !ok {
+		b = &breaker{changed: now}
+		h.breakers[addr+method] = b
+	}
+	switch b.state {
+	case breakerHalfOpen:
+		if b.probing > 0 {
+			b.probing--
+		}
+		if canceled {
+			break
+		}
+		if failed || slow {
+			*events = append(*events, h.setState(addr, method, b, breakerOpen, now))
+		} else if b.probed++; b.probed >= gp.probes {
+			*events = append(*events, h.setState(addr, method, b, breakerClosed, now))
+		}
+	case breakerClosed:
+		if canceled {
+			break
+		}
+		bk := &b.buckets[now.UnixNano()/int64(gp.window/breakerBuckets)%breakerBuckets]
+		if now.Sub(bk.start) >= gp.window/breakerBuckets {
+			*bk = bucket{start: now.Truncate(gp.window / breakerBuckets)}
+		}
+		bk.requests++
+		if failed {
+			bk.failures++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+		if slow {
+			bk.slows++
+		}
+		var requests, failures, slows int
+		for _, bk := range b.buckets {
+			if now.Sub(bk.start) < gp.window {
+				requests, failures, slows = requests+bk.requests, failures+bk.failures, slows+bk.slows
+			}
+		}
+		if b.consecutive >= gp.failures || requests >= gp.minRequests &&
+			(float64(failures) >= gp.errorRate*float64(requests) || gp.slowCall > 0 && float64(slows) >= gp.slowRate*float64(requests)) {
+			*events = append(*events, h.setState(addr, method, b, breakerOpen, now))
+		}
+	}
+}
+
+// setState changes the state of the breaker b of method on addr and returns the event.
+func (h *serviceHealth) setState(addr, method string, b *breaker, state int, now time.Time) string {
+	from := b.state
+	*b = breaker{state: state, changed: now}
+	breakerGauge(h.service, addr, method).Update(int64(state))
+	metrics.GetOrRegisterCounter(fmt.Sprintf("rpcx.client.%s.breaker.%s", h.service, breakerStates[state]),
+		metrics.DefaultRegistry).Inc(1)
+	return fmt.Sprintf("%s breaker of %s on %s: %s -> %s", h.service, method, addr, breakerStates[from], breakerStates[state])
+}
+
+// recordOutlier ejects addr after its consecutive failures, at most maxEject percent of the servers at once.
+func (h *serviceHealth) recordOutlier(addr string, o *outlier, gp *guardPolicy, failed bool, servers int, events *[]string) {
+	now := time.Now()
+	if !failed {
+		o.consecutive = 0
+		if now.After(o.ejectedUntil) {
+			o.ejections = 0
+		}
+		return
+	}
+	if o.consecutive++; o.consecutive < gp.ejectFailures || now.Before(o.ejectedUntil) {
+		return
+	}
+	ejected := 0
+	for _, n := range h.nodes {
+		if now.Before(n.ejectedUntil) {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > servers*gp.maxEject {
+		return
+	}
+	o.consecutive = 0
+	o.ejections++
+	o.ejectedUntil = now.Add(gp.ejectTime * time.Duration(o.ejections))
+	metrics.GetOrRegisterCounter("rpcx.client."+h.service+".ejections", metrics.DefaultRegistry).Inc(1)
+	*events = append(*events, fmt.Sprintf("%s server %s ejected for %s after %d failures", h.service, addr,
+		gp.ejectTime*time.Duration(o.ejections), gp.ejectFailures))
+}
+
+// report logs the events and sends them to the graceful robot if `service.client.report` is on,
+// they are logged at info since the logged warnings are sent to the error robot.
+func (h *serviceHealth) report(events []string) {
+	if len(events) == 0 {
+		return
+	}
+	c := framework.Config()
+	for _, event := range events {
+		log.Info(event)
+		if c != nil && c.Service.Client.Report {
+			go func(event string) {
+				defer safe.Defer()
+				sender.Graceful(event, false)
+			}(event)
+		}
+	}
+}
+
+// breakerGauge is the state of the breaker of method on addr: 0 closed, 1 open, 2 half-open.
+func breakerGauge(service, addr, method string) metrics.Gauge {
+	return metrics.GetOrRegisterGauge("rpcx.client."+service+".breaker."+addr+method, metrics.DefaultRegistry)
+}
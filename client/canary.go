@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rcrowley/go-metrics"
+	"github.com/smallnest/rpcx/share"
+)
+
+const (
+	canaryMetaKey    = types.MetaCanary // request metadata of the canary calls, "1", passed on down the calls
+	canaryDefaultKey = "user_id"
+)
+
+// canaryPolicy is the resolved canary policy of a call, nil when the servers are not split.
+type canaryPolicy struct {
+	percent float64
+	key     string
+	rules   []types.CanaryRule
+}
+
+func newCanaryPolicy(c types.CanaryPolicy) (*canaryPolicy, error) {
+	if !c.IsSet() {
+		return nil, nil
+	}
+	cp := &canaryPolicy{percent: c.Percent, key: c.Key, rules: c.Rules}
+	if cp.key == "" {
+		cp.key = canaryDefaultKey
+	}
+	for i, rule := range cp.rules {
+		if rule.Arg == "" || len(rule.Values) == 0 {
+			return nil, fmt.Errorf("canary rule %d without arg or values", i)
+		}
+	}
+	return cp, nil
+}
+
+// isCanary reports whether a call is canary: the calls of canary requests and of canary nodes are, the other ones
+// when cp matches their args. Only the services with a canary policy split their servers, the canary calls to the
+// other services only pass the canary flag on.
+func isCanary(ctx context.Context, cp *canaryPolicy, args map[string]interface{}) bool {
+	if inbound, ok := ctx.Value(share.ReqMetaDataKey).(map[string]string); ok && inbound[canaryMetaKey] == "1" {
+		return true
+	}
+	if framework.ServiceMetadataConfig().Tags[types.MetaCanary] == "true" {
+		return true
+	}
+	if cp == nil {
+		return false
+	}
+	for _, rule := range cp.rules {
+		if v, ok := hashKey(args, rule.Arg); ok {
+			for _, value := range rule.Values {
+				if v == value {
+					return true
+				}
+			}
+		}
+	}
+	if cp.percent <= 0 {
+		return false
+	}
+	if v, ok := hashKey(args, cp.key); ok {
+		return float64(crc32.ChecksumIEEE([]byte(v))%10000) < cp.percent*100
+	}
+	return rand.Float64()*100 < cp.percent
+}
+
+// canaryCounter counts the canary calls to service.
+func canaryCounter(service string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("rpcx.client."+service+".canary", metrics.DefaultRegistry)
+}
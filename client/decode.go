@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ZYallers/golib/funcs/conv"
+	"github.com/ZYallers/rpcx-framework/types"
+)
+
+// ReplyError is a types.Reply envelope whose code is not 200.
+type ReplyError struct {
+	Code int
+	Msg  string
+	Data interface{}
+}
+
+func (e *ReplyError) Error() string { return fmt.Sprintf("reply code %d: %s", e.Code, e.Msg) }
+
+// XClientInto calls serviceMethod of service like XClientCtx and decodes the reply into v, see Decode.
+func XClientInto(ctx context.Context, service, serviceMethod string, args map[string]interface{}, v interface{}, opts ...CallOption) (*Reply, error) {
+	reply, err := XClientCtx(ctx, service, serviceMethod, args, opts...)
+	if err != nil {
+		return reply, err
+	}
+	return reply, reply.Decode(v)
+}
+
+// Decode decodes the reply data into v, see Decode.
+func (r *Reply) Decode(v interface{}) error { return Decode(r.Data, v) }
+
+// Decode decodes a reply of XClient, HttpInvoke or JsonRpc2 into v, v may be nil to only check the envelope.
+// The types.Reply envelope is unwrapped by Unwrap, its data is decoded into v.
+func Decode(reply interface{}, v interface{}) error {
+	data, err := Unwrap(reply)
+	if err != nil || v == nil || data == nil {
+		return err
+	}
+	b, ok := data.(json.RawMessage)
+	if !ok {
+		if b, err = json.Marshal(normalize(data)); err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("reply decode error: %v", err)
+	}
+	return nil
+}
+
+// Unwrap returns the data of the types.Reply envelope of reply, a *ReplyError if its code is not 200.
+// The envelope is the JSON bytes replied over tcp by Service.Json, the struct replied over http or by
+// Service.Finish, which reaches the client as a map; replies without envelope are returned as is.
+func Unwrap(reply interface{}) (interface{}, error) {
+	switch r := reply.(type) {
+	case nil:
+		return nil, nil
+	case *types.Reply:
+		return envelope(r.Code, r.Msg, r.Data)
+	case types.Reply:
+		return envelope(r.Code, r.Msg, r.Data)
+	case string:
+		return unwrapJSON([]byte(r), reply)
+	case []byte:
+		return unwrapJSON(r, reply)
+	case map[string]interface{}, map[interface{}]interface{}:
+		m := normalize(r).(map[string]interface{})
+		code, hasCode := lookup(m, "code")
+		msg, hasMsg := lookup(m, "msg")
+		if !hasCode || !hasMsg {
+			return m, nil
+		}
+		data, _ := lookup(m, "data")
+		return envelope(conv.ToInt(code), conv.ToString(msg), data)
+	}
+	return reply, nil
+}
+
+func unwrapJSON(b []byte, reply interface{}) (interface{}, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		if json.Valid(b) {
+			return json.RawMessage(b), nil
+		}
+		return reply, nil
+	}
+	_, hasCode := m["code"]
+	_, hasMsg := m["msg"]
+	if !hasCode || !hasMsg {
+		return json.RawMessage(b), nil
+	}
+	var rep struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return nil, fmt.Errorf("reply envelope decode error: %v", err)
+	}
+	var data interface{}
+	if len(rep.Data) > 0 && string(rep.Data) != "null" {
+		data = rep.Data
+	}
+	return envelope(rep.Code, rep.Msg, data)
+}
+
+func envelope(code int, msg string, data interface{}) (interface{}, error) {
+	if code != http.StatusOK {
+		return nil, &ReplyError{Code: code, Msg: msg, Data: data}
+	}
+	return data, nil
+}
+
+// lookup finds key in m ignoring the case, msgpack encodes the struct fields by name and json by tag.
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// normalize converts the map[interface{}]interface{} decoded by msgpack to map[string]interface{} for json.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, sub := range x {
+			m[fmt.Sprint(k)] = normalize(sub)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, sub := range x {
+			m[k] = normalize(sub)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, sub := range x {
+			s[i] = normalize(sub)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rcrowley/go-metrics"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/share"
+)
+
+const (
+	hedgeDefaultPercentile = 95
+	hedgeDefaultBudget     = 0.1
+	hedgeMinSamples        = 100 // latencies before the percentile is used
+	hedgeMaxTokens         = 10
+)
+
+var hedgeBudgets = safe.NewDict()
+
+// hedgePolicy is the resolved hedge policy of a call, nil when it is not hedged.
+type hedgePolicy struct {
+	percentile float64
+	delay      time.Duration
+	budget     float64
+}
+
+func newHedgePolicy(h types.HedgePolicy, delay time.Duration) *hedgePolicy {
+	if h.Enabled == nil || !*h.Enabled {
+		return nil
+	}
+	hp := &hedgePolicy{percentile: hedgeDefaultPercentile, delay: delay, budget: hedgeDefaultBudget}
+	if h.Percentile > 0 {
+		hp.percentile = h.Percentile
+	}
+	if h.Delay > 0 {
+		hp.delay = time.Duration(h.Delay) * time.Millisecond
+	}
+	if h.Budget > 0 {
+		hp.budget = h.Budget
+	}
+	return hp
+}
+
+// after returns the delay of the hedge of a call of serviceMethod, the percentile of its latencies, at least delay.
+func (hp *hedgePolicy) after(service, serviceMethod string) time.Duration {
+	h := latencyHistogram(service, serviceMethod)
+	if h.Count() < hedgeMinSamples {
+		return hp.delay
+	}
+	if p := time.Duration(h.Percentile(hp.percentile / 100)); p > hp.delay {
+		return p
+	}
+	return hp.delay
+}
+
+// hedgeBudget earns budget tokens by call and spends one by hedge.
+type hedgeBudget struct {
+	mu     sync.Mutex
+	tokens float64
+}
+
+func budget(service, serviceMethod string) *hedgeBudget {
+	b := &hedgeBudget{}
+	if old, ok := hedgeBudgets.GetOrPut(service+serviceMethod, b); ok {
+		return old.(*hedgeBudget)
+	}
+	return b
+}
+
+func (b *hedgeBudget) earn(tokens float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.tokens+tokens, hedgeMaxTokens)
+}
+
+func (b *hedgeBudget) spend() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// hedge makes an attempt of a call and, if it is still running after the hedge delay and the budget allows,
+// the same request to another server. The first success is the reply, the other request is canceled.
+func hedge(ctx context.Context, l *lease, xp *xClientProfile, canary bool, service, serviceMethod string, args interface{},
+	reply *interface{}, call func(xc client.XClient, ctx context.Context, serviceMethod string, args, reply interface{}) error) error {
+	type result struct {
+		data    interface{}
+		meta    map[string]string
+		err     error
+		hedged  bool
+		elapsed time.Duration
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, 2)
+	send := func(node *callNode, hedged bool) {
+		go func() {
+			var r result
+			defer func() {
+				if p := recover(); p != nil {
+					r.err = fmt.Errorf("xclient recover: %v", p)
+				}
+				results <- r
+			}()
+			// rpcx writes the metadata of the requests, each one has its own maps
+			reqMeta, _ := ctx.Value(share.ReqMetaDataKey).(map[string]string)
+			req := make(map[string]string, len(reqMeta))
+			for k, v := range reqMeta {
+				req[k] = v
+			}
+			r.meta = map[string]string{}
+			ctx := context.WithValue(context.WithValue(ctx, share.ReqMetaDataKey, req), share.ResMetaDataKey, r.meta)
+			start := time.Now()
+			r.err = attempt(ctx, l, xp.retry, node, serviceMethod, args, &r.data, call)
+			r.hedged, r.elapsed = hedged, time.Since(start)
+		}()
+	}
+
+	b := budget(service, serviceMethod)
+	b.earn(xp.hedge.budget)
+	first := &callNode{canary: canary}
+	send(first, false)
+	timer := time.NewTimer(xp.hedge.after(service, serviceMethod))
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			if b.spend() {
+				hedgeCounter(service, "hedges").Inc(1)
+				send(&callNode{canary: canary, exclude: first.selected()}, true)
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				latencyHistogram(service, serviceMethod).Update(r.elapsed.Nanoseconds())
+				if r.hedged {
+					hedgeCounter(service, "hedge_wins").Inc(1)
+				}
+				*reply = r.data
+				if resMeta, ok := ctx.Value(share.ResMetaDataKey).(map[string]string); ok {
+					for k, v := range r.meta {
+						resMeta[k] = v
+					}
+				}
+				return nil
+			}
+			// a failure before the hedge delay is left to the retry policy
+			if pending == 0 {
+				return r.err
+			}
+		}
+	}
+}
+
+// latencyHistogram holds the latencies of the successful calls of serviceMethod, in nanoseconds.
+func latencyHistogram(service, serviceMethod string) metrics.Histogram {
+	return metrics.GetOrRegisterHistogram("rpcx.client."+service+".latency"+serviceMethod, metrics.DefaultRegistry,
+		metrics.NewExpDecaySample(1028, 0.015))
+}
+
+// hedgeCounter counts the hedges sent to service and the ones replying first.
+func hedgeCounter(service, name string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("rpcx.client."+service+"."+name, metrics.DefaultRegistry)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	framework "github.com/ZYallers/rpcx-framework"
+	errors2 "github.com/ZYallers/rpcx-framework/errors"
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+)
+
+var nodeFilterDict = safe.NewDict()
+
+// Node is a server of a service and the metadata it publishes in the registry.
+type Node struct {
+	Addr     string // e.g. "tcp@10.0.0.1:9001"
+	Metadata *types.Metadata
+}
+
+// NodeFilter reports whether a node may be called.
+type NodeFilter func(n Node) bool
+
+// WithVersion keeps the nodes of the service version.
+func WithVersion(version string) NodeFilter {
+	return func(n Node) bool { return n.Metadata.Version == version }
+}
+
+// WithZone keeps the nodes of the zone.
+func WithZone(zone string) NodeFilter {
+	return func(n Node) bool { return n.Metadata.Zone == zone }
+}
+
+// WithGroup keeps the nodes of the group.
+func WithGroup(group string) NodeFilter {
+	return func(n Node) bool { return n.Metadata.Group == group }
+}
+
+// WithHandler keeps the nodes serving handlers of the version.
+func WithHandler(version string) NodeFilter {
+	return func(n Node) bool { return n.Metadata.HasHandler(version) }
+}
+
+// WithTag keeps the nodes tagged with value.
+func WithTag(key, value string) NodeFilter {
+	return func(n Node) bool { return n.Metadata.Tags[key] == value }
+}
+
+// Nodes returns the servers of service in the registry of the run mode, filtered by SetNodeFilter.
+func Nodes(service string) ([]Node, error) {
+	sd := framework.ServiceDiscovery()
+	if sd == nil {
+		return nil, errors2.ErrServiceDiscoveryNotMeeting
+	}
+	l, err := pool.acquire(sd, service, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer l.release()
+	pairs := l.gen.discovery.GetServices()
+	nodes := make([]Node, 0, len(pairs))
+	for _, pair := range pairs {
+		nodes = append(nodes, newNode(pair))
+	}
+	return nodes, nil
+}
+
+// FilterNodes returns the nodes passing every filter.
+func FilterNodes(nodes []Node, filters ...NodeFilter) []Node {
+	var res []Node
+	for _, n := range nodes {
+		if matchNode(n, filters) {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// SetNodeFilter restricts the servers of service called by XClient to the ones passing every filter,
+// no filter removes the restriction.
+func SetNodeFilter(service string, filters ...NodeFilter) {
+	var filter client.ServiceDiscoveryFilter
+	if len(filters) > 0 {
+		filter = func(pair *client.KVPair) bool { return matchNode(newNode(pair), filters) }
+		nodeFilterDict.Put(service, filter)
+	} else {
+		nodeFilterDict.Delete(service)
+	}
+	if dis := pool.discovery(service); dis != nil {
+		dis.SetFilter(filter)
+	}
+}
+
+// nodeFilter returns the filter set for service, nil if none.
+func nodeFilter(service string) client.ServiceDiscoveryFilter {
+	if v, ok := nodeFilterDict.Get(service); ok {
+		return v.(client.ServiceDiscoveryFilter)
+	}
+	return nil
+}
+
+func newNode(pair *client.KVPair) Node {
+	return Node{Addr: pair.Key, Metadata: types.ParseMetadata(pair.Value)}
+}
+
+func matchNode(n Node, filters []NodeFilter) bool {
+	for _, f := range filters {
+		if !f(n) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/protocol"
+)
+
+var (
+	serializeTypes = map[string]protocol.SerializeType{
+		"msgpack": protocol.MsgPack,
+		"json":    protocol.JSON,
+	}
+	compressTypes = map[string]protocol.CompressType{
+		"none": protocol.None,
+		"gzip": protocol.Gzip,
+	}
+)
+
+// CallOption overrides the client profile of the target service for one call.
+type CallOption func(o *callOptions)
+
+type callOptions struct {
+	profile  types.ClientProfile
+	metadata map[string]string
+}
+
+// WithTimeout sets the timeout of the call.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) { o.profile.Timeout = timeout.Milliseconds() }
+}
+
+// WithRetries sets the retries of the call when the retry policy has no max attempts.
+func WithRetries(retries int) CallOption {
+	return func(o *callOptions) { o.profile.Retries = &retries }
+}
+
+// WithRetryPolicy sets the retry policy of the call, its unset fields are taken from the profile.
+func WithRetryPolicy(policy types.RetryPolicy) CallOption {
+	return func(o *callOptions) { o.profile.Retry = policy.Merge(o.profile.Retry) }
+}
+
+// WithIdempotent declares whether the call is safe to retry on the retryOn error classes of its policy,
+// timeout and network by default, over what the servers declare.
+func WithIdempotent(idempotent bool) CallOption {
+	return func(o *callOptions) { o.profile.Retry.Idempotent = &idempotent }
+}
+
+// WithFailMode sets the fail mode: failover, failfast, failtry or failbackup.
+func WithFailMode(mode string) CallOption {
+	return func(o *callOptions) { o.profile.FailMode = mode }
+}
+
+// WithSelectMode sets the select mode: random, roundrobin, weightedroundrobin, weightedicmp, consistenthash or closest.
+func WithSelectMode(mode string) CallOption {
+	return func(o *callOptions) { o.profile.SelectMode = mode }
+}
+
+// WithSelector selects the servers by their metadata instead of the select mode, its unset fields are taken
+// from the profile.
+func WithSelector(selector types.SelectorConfig) CallOption {
+	return func(o *callOptions) { o.profile.Selector = selector.Merge(o.profile.Selector) }
+}
+
+// WithHashKey selects the server of the call by consistent hashing on the arg key, e.g. "user_id".
+func WithHashKey(key string) CallOption {
+	return func(o *callOptions) { o.profile.Selector.Balance, o.profile.Selector.HashKey = balanceHash, key }
+}
+
+// WithCanary sets the canary policy of the call, its unset fields are taken from the profile.
+func WithCanary(canary types.CanaryPolicy) CallOption {
+	return func(o *callOptions) { o.profile.Canary = canary.Merge(o.profile.Canary) }
+}
+
+// WithHedge sets the hedge policy of the call, its unset fields are taken from the profile.
+func WithHedge(hedge types.HedgePolicy) CallOption {
+	return func(o *callOptions) { o.profile.Hedge = hedge.Merge(o.profile.Hedge) }
+}
+
+// withoutHedge disables the hedges of the calls sent to several servers.
+func withoutHedge() CallOption {
+	disabled := false
+	return func(o *callOptions) { o.profile.Hedge.Enabled = &disabled }
+}
+
+// WithSerializeType sets the serialization of the payload: msgpack or json.
+func WithSerializeType(typ string) CallOption {
+	return func(o *callOptions) { o.profile.SerializeType = typ }
+}
+
+// WithCompressType sets the compression of the payload: none or gzip.
+func WithCompressType(typ string) CallOption {
+	return func(o *callOptions) { o.profile.CompressType = typ }
+}
+
+// WithBreaker sets the consecutive failures opening the breaker of a method of a server and how long it stays
+// open before the probes, 0 failures disables it.
+func WithBreaker(failures int, window time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.profile.BreakerFailures, o.profile.BreakerWindow = &failures, window.Milliseconds()
+	}
+}
+
+// WithMetadata adds request metadata to the call, over the forwarded one.
+func WithMetadata(metadata map[string]string) CallOption {
+	return func(o *callOptions) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			o.metadata[k] = v
+		}
+	}
+}
+
+// xClientProfile is the resolved client profile of a call.
+type xClientProfile struct {
+	timeout    time.Duration
+	forward    []string
+	metadata   map[string]string
+	failMode   client.FailMode
+	selectMode client.SelectMode
+	option     client.Option
+	retry      *retryPolicy
+	guard      *guardPolicy
+	selector   *selectorPolicy
+	canary     *canaryPolicy
+	hedge      *hedgePolicy
+	key        string // identifies the XClients sharing the profile, the timeout aside
+}
+
+// callProfile resolves the profile of a call of serviceMethod to service: call options, method profile, service
+// profile, default profile, run mode profile, then the built-in values.
+func callProfile(service, serviceMethod string, opts []CallOption) (*xClientProfile, error) {
+	o := &callOptions{}
+	forward := xClientForwardKeys
+	if c := framework.Config(); c != nil {
+		o.profile = c.MethodProfile(service, serviceMethod)
+		if len(c.Service.Client.Forward) > 0 {
+			forward = c.Service.Client.Forward
+		}
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	p := o.profile
+
+	modeFailMode, modeSelectMode := xClientModes()
+	xp := &xClientProfile{timeout: xClientDefaultTimeout, forward: forward, metadata: o.metadata,
+		failMode: modeFailMode, selectMode: modeSelectMode, option: xClientOption}
+	if p.Timeout > 0 {
+		xp.timeout = time.Duration(p.Timeout) * time.Millisecond
+	}
+	if p.ConnectTimeout > 0 {
+		xp.option.ConnectTimeout = time.Duration(p.ConnectTimeout) * time.Millisecond
+	}
+	// the calls are retried by the retry policy, which knows what is safe to retry
+	retries := xp.option.Retries
+	if p.Retries != nil {
+		retries = *p.Retries
+	}
+	xp.option.Retries = 0
+	retry, err := newRetryPolicy(p.Retry, retries+1)
+	if err != nil {
+		return nil, err
+	}
+	xp.retry = retry
+	if p.FailMode != "" {
+		mode, ok := failModes[strings.ToLower(p.FailMode)]
+		if !ok {
+			return nil, fmt.Errorf("unknown fail mode %q", p.FailMode)
+		}
+		xp.failMode = mode
+	}
+	if xp.failMode == client.Failover {
+		// the retry policy selects another server on every attempt, rpcx failover without retries selects twice
+		xp.failMode = client.Failfast
+	}
+	if p.SelectMode != "" {
+		mode, ok := selectModes[strings.ToLower(p.SelectMode)]
+		if !ok {
+			return nil, fmt.Errorf("unknown select mode %q", p.SelectMode)
+		}
+		xp.selectMode = mode
+	}
+	if p.SerializeType != "" {
+		typ, ok := serializeTypes[strings.ToLower(p.SerializeType)]
+		if !ok {
+			return nil, fmt.Errorf("unknown serialize type %q", p.SerializeType)
+		}
+		xp.option.SerializeType = typ
+	}
+	if p.CompressType != "" {
+		typ, ok := compressTypes[strings.ToLower(p.CompressType)]
+		if !ok {
+			return nil, fmt.Errorf("unknown compress type %q", p.CompressType)
+		}
+		xp.option.CompressType = typ
+	}
+	if p.BackupLatency > 0 {
+		xp.option.BackupLatency = time.Duration(p.BackupLatency) * time.Millisecond
+	}
+	if xp.canary, err = newCanaryPolicy(p.Canary); err != nil {
+		return nil, err
+	}
+	if xp.selector, err = newSelectorPolicy(p.Selector, xp.canary != nil); err != nil {
+		return nil, err
+	}
+	xp.guard = newGuardPolicy(p)
+	if xp.failMode == client.Failbackup && (xp.guard.failures > 0 || xp.guard.ejectFailures > 0) {
+		// rpcx selects the server of the backup request on its own, the outcome of a call has no single server
+		return nil, fmt.Errorf("failbackup mode of %s needs breakerFailures and outlier.failures 0, the hedge policy "+
+			"sends the backup requests otherwise", service)
+	}
+	xp.hedge = newHedgePolicy(p.Hedge, xp.option.BackupLatency)
+
+	xp.key = fmt.Sprintf("%s#%d/%d/%d/%d/%d/%d/%+v/%+v", service, xp.failMode, xp.selectMode, xp.option.ConnectTimeout,
+		xp.option.SerializeType, xp.option.CompressType, xp.option.BackupLatency, *xp.guard, xp.selector)
+	return xp, nil
+}
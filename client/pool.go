@@ -0,0 +1,199 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	"github.com/ZYallers/rpcx-framework/registry"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rcrowley/go-metrics"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/log"
+)
+
+// pool owns the discoveries and XClients of the target services. A generation of a service holds its discovery
+// and one XClient by profile, it is retired when the registry config changes, when it is older than
+// `service.client.maxAge` or on Close, and closed once its in-flight calls are done.
+var pool = &clientPool{services: map[string]*generation{}}
+
+type clientPool struct {
+	mu       sync.Mutex
+	services map[string]*generation
+}
+
+type generation struct {
+	service   string
+	sd        types.Discovery // the registry config the discovery was made from
+	discovery client.ServiceDiscovery
+	clients   map[string]client.XClient // by profile key
+	created   time.Time
+	refs      int // in-flight calls
+	retired   bool
+}
+
+// lease holds a generation for a call, it must be released once the call is done.
+type lease struct {
+	client.XClient // nil when no profile was given
+	gen            *generation
+	once           sync.Once
+}
+
+func (l *lease) release() { l.once.Do(func() { pool.release(l.gen) }) }
+
+// Close closes the clients of service once their in-flight calls are done, later calls open new ones.
+func Close(service string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if gen, ok := pool.services[service]; ok {
+		pool.retire(gen, "closed")
+	}
+}
+
+// CloseAll closes the clients of every service once their in-flight calls are done, e.g. on shutdown.
+func CloseAll() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, gen := range pool.services {
+		pool.retire(gen, "closed")
+	}
+}
+
+// acquire leases the current generation of service and its XClient of profile xp.
+func (p *clientPool) acquire(sd *types.Discovery, service string, xp *xClientProfile) (*lease, error) {
+	p.mu.Lock()
+	if gen := p.current(sd, service); gen != nil {
+		defer p.mu.Unlock()
+		return p.hold(gen, xp), nil
+	}
+	p.mu.Unlock()
+
+	// the registry may take a while to answer, the other services are not held up meanwhile
+	dis, err := registry.Discovery(sd, service)
+	if err != nil {
+		return nil, fmt.Errorf("new %s discovery error: %v", sd.Type, err)
+	}
+	p.mu.Lock()
+	gen := p.current(sd, service)
+	made := gen == nil
+	if made {
+		gen = &generation{service: service, sd: *sd, clients: map[string]client.XClient{}, created: time.Now(),
+			discovery: registry.NewFilterDiscovery(dis, nodeFilter(service))}
+		p.services[service] = gen
+		poolCounter(service, "generations").Inc(1)
+	}
+	// leased before unlocking, a generation is never retired and closed between its lookup and its lease
+	l := p.hold(gen, xp)
+	p.mu.Unlock()
+	if !made {
+		dis.Close()
+	}
+	return l, nil
+}
+
+// hold leases gen, which is current, and its XClient of profile xp, p.mu is held.
+func (p *clientPool) hold(gen *generation, xp *xClientProfile) *lease {
+	l := &lease{gen: gen}
+	if xp != nil {
+		xc, ok := gen.clients[xp.key]
+		if !ok {
+			xc = client.NewXClient(gen.service, xp.failMode, xp.selectMode, gen.discovery, xp.option)
+			if xp.selector != nil {
+				xc.SetSelector(newMetaSelector(xp.selector))
+			}
+			xc.GetPlugins().Add(&nodeGuard{health: health(gen.service), policy: xp.guard, discovery: gen.discovery})
+			gen.clients[xp.key] = xc
+			poolCounter(gen.service, "xclients").Inc(1)
+		}
+		l.XClient = xc
+	}
+	gen.refs++
+	poolGauge(gen.service).Update(int64(gen.refs))
+	return l
+}
+
+// current returns the generation of service in use, nil when there is none, retiring the one out of date.
+func (p *clientPool) current(sd *types.Discovery, service string) *generation {
+	gen, ok := p.services[service]
+	if !ok {
+		return nil
+	}
+	if !reflect.DeepEqual(gen.sd, *sd) {
+		p.retire(gen, "registry changed")
+		return nil
+	}
+	if maxAge := clientMaxAge(); maxAge > 0 && time.Since(gen.created) > maxAge {
+		p.retire(gen, "max age reached")
+		return nil
+	}
+	return gen
+}
+
+func (p *clientPool) release(gen *generation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	gen.refs--
+	if cur, ok := p.services[gen.service]; ok && cur == gen {
+		poolGauge(gen.service).Update(int64(gen.refs))
+	}
+	if gen.retired && gen.refs == 0 {
+		gen.close()
+	}
+}
+
+// retire stops leasing gen, it is closed now if idle or else by the release of its last call.
+func (p *clientPool) retire(gen *generation, reason string) {
+	if gen.retired {
+		return
+	}
+	gen.retired = true
+	if cur, ok := p.services[gen.service]; ok && cur == gen {
+		delete(p.services, gen.service)
+		poolGauge(gen.service).Update(0)
+	}
+	poolCounter(gen.service, "retired").Inc(1)
+	log.Infof("%s clients retired after %s: %s", gen.service, time.Since(gen.created).Round(time.Second), reason)
+	if gen.refs == 0 {
+		gen.close()
+	}
+}
+
+func (gen *generation) close() {
+	for _, xc := range gen.clients {
+		if err := xc.Close(); err != nil {
+			log.Warnf("%s xclient close error: %v", gen.service, err)
+		}
+	}
+	gen.discovery.Close()
+	poolCounter(gen.service, "closed").Inc(1)
+}
+
+// discovery returns the discovery of the generation of service in use, nil if none.
+func (p *clientPool) discovery(service string) client.ServiceDiscovery {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if gen, ok := p.services[service]; ok {
+		return gen.discovery
+	}
+	return nil
+}
+
+func clientMaxAge() time.Duration {
+	if c := framework.Config(); c != nil {
+		return time.Duration(c.Service.Client.MaxAge) * time.Second
+	}
+	return 0
+}
+
+// poolCounter counts the generations made, retired and closed and the XClients made for service,
+// e.g. "rpcx.client.user.retired" in the default go-metrics registry.
+func poolCounter(service, name string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("rpcx.client."+service+"."+name, metrics.DefaultRegistry)
+}
+
+// poolGauge is the number of in-flight calls to service.
+func poolGauge(service string) metrics.Gauge {
+	return metrics.GetOrRegisterGauge("rpcx.client."+service+".inflight", metrics.DefaultRegistry)
+}
@@ -0,0 +1,13 @@
+package client
+
+import "time"
+
+// Reply is the envelope of the reply of a call made by XClientCtx.
+type Reply struct {
+	Service  string
+	Method   string
+	Data     interface{}       // reply decoded by the codec, a map for msgpack
+	Metadata map[string]string // response metadata set by the server
+	Attempts int               // made by the retry policy
+	Elapsed  time.Duration
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rcrowley/go-metrics"
+	"github.com/smallnest/rpcx/client"
+)
+
+// Error classes of the failed calls, see types.RetryPolicy.
+const (
+	retryUnavailable = "unavailable" // the call was not sent: no server, breaker open, dial error
+	retryTimeout     = "timeout"
+	retryNetwork     = "network"
+	retryServer      = "server" // the handler failed
+)
+
+const (
+	retryDefaultBackoff    = 50 * time.Millisecond
+	retryDefaultMaxBackoff = time.Second
+	retryDefaultJitter     = 0.2
+)
+
+var retryDefaultOn = []string{retryTimeout, retryNetwork}
+
+// retryPolicy is the resolved retry policy of a call.
+type retryPolicy struct {
+	attempts       int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	jitter         float64
+	attemptTimeout time.Duration
+	retryOn        map[string]bool
+	idempotent     *bool
+}
+
+// newRetryPolicy resolves p, attempts is used when p has no max attempts.
+func newRetryPolicy(p types.RetryPolicy, attempts int) (*retryPolicy, error) {
+	rp := &retryPolicy{attempts: attempts, backoff: retryDefaultBackoff, maxBackoff: retryDefaultMaxBackoff,
+		jitter: retryDefaultJitter, idempotent: p.Idempotent, retryOn: map[string]bool{}}
+	if p.MaxAttempts > 0 {
+		rp.attempts = p.MaxAttempts
+	}
+	if p.Backoff > 0 {
+		rp.backoff = time.Duration(p.Backoff) * time.Millisecond
+	}
+	if p.MaxBackoff > 0 {
+		rp.maxBackoff = time.Duration(p.MaxBackoff) * time.Millisecond
+	}
+	if p.Jitter > 0 {
+		rp.jitter = p.Jitter
+	}
+	if p.AttemptTimeout > 0 {
+		rp.attemptTimeout = time.Duration(p.AttemptTimeout) * time.Millisecond
+	}
+	retryOn := p.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = retryDefaultOn
+	}
+	for _, class := range retryOn {
+		switch class = strings.ToLower(class); class {
+		case retryUnavailable, retryTimeout, retryNetwork, retryServer:
+			rp.retryOn[class] = true
+		default:
+			return nil, fmt.Errorf("unknown retry error class %q", class)
+		}
+	}
+	return rp, nil
+}
+
+// retryable reports whether the call of serviceMethod failing with err may be retried. The calls which were not
+// sent are always safe, the other ones only if serviceMethod is idempotent.
+func (rp *retryPolicy) retryable(err error, gen *generation, serviceMethod string) bool {
+	class := errorClass(err)
+	if class == retryUnavailable {
+		return true
+	}
+	if class == "" || !rp.retryOn[class] {
+		return false
+	}
+	return rp.safe(gen, serviceMethod)
+}
+
+// safe reports whether serviceMethod may be sent twice, as declared by the policy or else by the servers.
+func (rp *retryPolicy) safe(gen *generation, serviceMethod string) bool {
+	if rp.idempotent != nil {
+		return *rp.idempotent
+	}
+	return idempotent(gen, serviceMethod)
+}
+
+// wait sleeps the backoff before attempt+1, false if ctx is done first.
+func (rp *retryPolicy) wait(ctx context.Context, attempt int) bool {
+	d := rp.backoff
+	for i := 1; i < attempt && d < rp.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > rp.maxBackoff {
+		d = rp.maxBackoff
+	}
+	if rp.jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * rp.jitter * float64(d))
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// errorClass classifies err, "" for the calls canceled by the caller.
+func errorClass(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return ""
+	}
+	if errors.Is(err, client.ErrXClientNoServer) || errors.Is(err, client.ErrServerUnavailable) ||
+		errors.Is(err, client.ErrBreakerOpen) || errors.Is(err, client.ErrXClientShutdown) {
+		return retryUnavailable
+	}
+	var se client.ServiceError
+	if errors.As(err, &se) {
+		return retryServer
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return retryTimeout
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) && oe.Op == "dial" {
+		return retryUnavailable
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return retryTimeout
+	}
+	return retryNetwork
+}
+
+// idempotent reports whether every node of gen declares serviceMethod idempotent.
+func idempotent(gen *generation, serviceMethod string) bool {
+	pairs := gen.discovery.GetServices()
+	if len(pairs) == 0 {
+		return false
+	}
+	for _, kv := range pairs {
+		if !types.ParseMetadata(kv.Value).IsIdempotent(serviceMethod) {
+			return false
+		}
+	}
+	return true
+}
+
+// retryCounter counts the retries of the calls to service.
+func retryCounter(service string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("rpcx.client."+service+".retries", metrics.DefaultRegistry)
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	"github.com/ZYallers/rpcx-framework/types"
+)
+
+// Balances of types.SelectorConfig.
+const (
+	balanceRoundRobin = "roundrobin"
+	balanceRandom     = "random"
+	balanceHash       = "hash"
+)
+
+// hashReplicas are the points of a server of weight 1 on the hash ring.
+const hashReplicas = 64
+
+// selectorPolicy is the resolved selector of a call, nil for the rpcx select mode.
+type selectorPolicy struct {
+	balance string
+	hashKey string
+	zone    string // of the caller, when preferred
+	version string
+	canary  bool // splits the canary servers from the other ones
+}
+
+func newSelectorPolicy(s types.SelectorConfig, canary bool) (*selectorPolicy, error) {
+	if !s.IsSet() && !canary {
+		return nil, nil
+	}
+	sp := &selectorPolicy{balance: strings.ToLower(s.Balance), hashKey: s.HashKey, version: s.Version, canary: canary}
+	switch sp.balance {
+	case "":
+		if sp.balance = balanceRoundRobin; sp.hashKey != "" {
+			sp.balance = balanceHash
+		}
+	case balanceRoundRobin, balanceRandom:
+	case balanceHash:
+		if sp.hashKey == "" {
+			return nil, fmt.Errorf("selector hash balance without hash key")
+		}
+	default:
+		return nil, fmt.Errorf("unknown selector balance %q", s.Balance)
+	}
+	if s.ZoneAffinity != nil && *s.ZoneAffinity {
+		sp.zone = framework.ServiceMetadataConfig().Zone
+	}
+	return sp, nil
+}
+
+// metaSelector is the rpcx selector of a selectorPolicy: it keeps the servers of the version and of the class of
+// the call, canary or not, prefers the ones of the zone, and balances the calls by the weights the servers publish.
+type metaSelector struct {
+	policy selectorPolicy
+	mu     sync.Mutex
+	sets   [2]nodeSets // of the other servers and of the canary ones, the first holds all of them without split
+}
+
+type nodeSets struct {
+	all   *nodeSet // nil if none
+	local *nodeSet // of the zone, nil if none
+}
+
+type weightedNode struct {
+	addr    string
+	weight  int
+	current int // of the smooth weighted round robin
+}
+
+type nodeSet struct {
+	nodes []*weightedNode
+	total int
+	ring  []ringPoint // of the hash balance, sorted
+}
+
+type ringPoint struct {
+	hash uint32
+	node *weightedNode
+}
+
+func newMetaSelector(sp *selectorPolicy) *metaSelector { return &metaSelector{policy: *sp} }
+
+func (s *metaSelector) UpdateServer(servers map[string]string) {
+	var all, local [2][]*weightedNode
+	for addr, meta := range servers {
+		m := types.ParseMetadata(meta)
+		if m.Weight <= 0 || s.policy.version != "" && m.Version != s.policy.version {
+			continue
+		}
+		class := 0
+		if s.policy.canary && m.IsCanary() {
+			class = 1
+		}
+		n := &weightedNode{addr: addr, weight: m.Weight}
+		all[class] = append(all[class], n)
+		if s.policy.zone != "" && m.Zone == s.policy.zone {
+			local[class] = append(local[class], n)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for class := range s.sets {
+		s.sets[class] = nodeSets{all: s.newNodeSet(all[class]), local: s.newNodeSet(local[class])}
+	}
+}
+
+func (s *metaSelector) newNodeSet(nodes []*weightedNode) *nodeSet {
+	if len(nodes) == 0 {
+		return nil
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].addr < nodes[j].addr })
+	set := &nodeSet{nodes: nodes}
+	for _, n := range nodes {
+		set.total += n.weight
+	}
+	if s.policy.balance == balanceHash {
+		for _, n := range nodes {
+			for i := 0; i < hashReplicas*n.weight; i++ {
+				set.ring = append(set.ring, ringPoint{hash: crc32.ChecksumIEEE([]byte(n.addr + "#" + strconv.Itoa(i))), node: n})
+			}
+		}
+		sort.Slice(set.ring, func(i, j int) bool { return set.ring[i].hash < set.ring[j].hash })
+	}
+	return set
+}
+
+func (s *metaSelector) Select(ctx context.Context, servicePath, serviceMethod string, args interface{}) string {
+	call, _ := ctx.Value(callNodeKey{}).(*callNode)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	class := 0
+	if call != nil && call.canary && s.sets[1].all != nil || s.sets[0].all == nil {
+		class = 1
+	}
+	set := s.sets[class].all
+	// the servers of the zone first, the other ones once nodeGuard skipped as many servers as the zone has
+	if local := s.sets[class].local; local != nil && (call == nil || call.skipped() < len(local.nodes)) {
+		set = local
+	}
+	if set == nil {
+		return ""
+	}
+	switch s.policy.balance {
+	case balanceHash:
+		if key, ok := hashKey(args, s.policy.hashKey); ok {
+			h := crc32.ChecksumIEEE([]byte(key))
+			i := sort.Search(len(set.ring), func(i int) bool { return set.ring[i].hash >= h })
+			// the servers skipped by nodeGuard are passed over to the next ones of the ring
+			skips := 0
+			if call != nil {
+				skips = call.skipped() % len(set.nodes)
+			}
+			seen := make(map[*weightedNode]bool, skips+1)
+			for j := 0; ; j++ {
+				n := set.ring[(i+j)%len(set.ring)].node
+				if seen[n] {
+					continue
+				}
+				if len(seen) == skips {
+					return n.addr
+				}
+				seen[n] = true
+			}
+		}
+	case balanceRandom:
+		r := rand.Intn(set.total)
+		for _, n := range set.nodes {
+			if r -= n.weight; r < 0 {
+				return n.addr
+			}
+		}
+	}
+	// smooth weighted round robin, the calls without hash key included
+	var best *weightedNode
+	for _, n := range set.nodes {
+		n.current += n.weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	best.current -= set.total
+	return best.addr
+}
+
+// hashKey returns the arg key of the args of a call.
+func hashKey(args interface{}, key string) (string, bool) {
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	v, ok := m[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
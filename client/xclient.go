@@ -2,31 +2,41 @@ package client
 
 import (
 	"context"
-	"crypto/rand"
+	"errors"
 	"fmt"
-	"math/big"
+	"strings"
 	"time"
 
 	framework "github.com/ZYallers/rpcx-framework"
-	"github.com/ZYallers/rpcx-framework/consts"
 	errors2 "github.com/ZYallers/rpcx-framework/errors"
-	"github.com/ZYallers/rpcx-framework/helper/safe"
-	"github.com/ZYallers/rpcx-framework/helper/sender"
-	client2 "github.com/rpcxio/rpcx-etcd/client"
 	"github.com/smallnest/rpcx/client"
 	"github.com/smallnest/rpcx/protocol"
 	"github.com/smallnest/rpcx/share"
 )
 
 const (
-	xClientDefaultTimeout = 15 * time.Second
+	xClientDefaultTimeout  = 15 * time.Second
+	xClientBreakerFailures = 10
+	xClientBreakerWindow   = 10 * time.Second
 )
 
+var xClientForwardKeys = []string{"request_id", "trace_id"}
+
 var (
-	discoveryDict = safe.NewDict()
-	xClientDict   = safe.NewDict()
-	failMode      = client.Failover
-	selectMode    = client.RoundRobin
+	failModes = map[string]client.FailMode{
+		"failover":   client.Failover,
+		"failfast":   client.Failfast,
+		"failtry":    client.Failtry,
+		"failbackup": client.Failbackup,
+	}
+	selectModes = map[string]client.SelectMode{
+		"random":             client.RandomSelect,
+		"roundrobin":         client.RoundRobin,
+		"weightedroundrobin": client.WeightedRoundRobin,
+		"weightedicmp":       client.WeightedICMP,
+		"consistenthash":     client.ConsistentHash,
+		"closest":            client.Closest,
+	}
 	xClientOption = client.Option{
 		Retries:            3,                     // sets retries to send
 		RPCPath:            share.DefaultRPCPath,  // sets for http connection
@@ -36,22 +46,42 @@ var (
 		BackupLatency:      10 * time.Millisecond, // is used for Failbackup mode, rpcx will sends another request if the first response doesn't return in BackupLatency time
 		TCPKeepAlivePeriod: time.Minute,           // if it is zero we don't set keepalive
 		IdleTimeout:        xClientDefaultTimeout, // ReadTimeout sets max idle time for underlying net.Conns
-		GenBreaker: func() client.Breaker {
-			// if failed 10 times, return error immediately, and will try to connect after 60 seconds
-			return client.NewConsecCircuitBreaker(10, time.Minute)
-		}, // is used to config CircuitBreaker
+		// the breakers are the ones of nodeGuard, by server and method
 	}
 )
 
-func init() {
-	switch framework.ServiceMode() {
-	case consts.DevelopMode:
-		failMode = client.Failfast
-		selectMode = client.RandomSelect
+// xClientModes returns the fail and select mode of the run mode profile, failover and round robin by default.
+func xClientModes() (client.FailMode, client.SelectMode) {
+	profile := framework.ServiceModeConfig()
+	failMode, ok := failModes[strings.ToLower(profile.FailMode)]
+	if !ok {
+		failMode = client.Failover
+	}
+	selectMode, ok := selectModes[strings.ToLower(profile.SelectMode)]
+	if !ok {
+		selectMode = client.RoundRobin
+	}
+	return failMode, selectMode
+}
+
+// XClient calls serviceMethod of service with the client profile of service in `service.client`, opts override it.
+func XClient(service, serviceMethod string, args map[string]interface{}, opts ...CallOption) (reply interface{}, err error) {
+	r, err := XClientCtx(context.Background(), service, serviceMethod, args, opts...)
+	if r != nil {
+		reply = r.Data
 	}
+	return
+}
+
+// XClientCtx calls serviceMethod of service like XClient within the deadline of ctx, at most the profile timeout.
+// The `service.client.forward` metadata of the inbound request of ctx is passed on with the WithMetadata one.
+func XClientCtx(ctx context.Context, service, serviceMethod string, args map[string]interface{}, opts ...CallOption) (*Reply, error) {
+	return invoke(ctx, service, serviceMethod, args, opts, client.XClient.Call)
 }
 
-func XClient(service, serviceMethod string, args map[string]interface{}) (reply interface{}, err error) {
+// invoke makes a call of service through call, Call, Broadcast or Fork of its XClient.
+func invoke(ctx context.Context, service, serviceMethod string, args map[string]interface{}, opts []CallOption,
+	call func(xc client.XClient, ctx context.Context, serviceMethod string, args, reply interface{}) error) (reply *Reply, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("xclient recover: %v", r)
@@ -59,82 +89,87 @@ func XClient(service, serviceMethod string, args map[string]interface{}) (reply
 	}()
 
 	sd := framework.ServiceDiscovery()
-	if sd == nil || sd.BasePath == "" || len(sd.Addr) == 0 {
+	if sd == nil {
 		err = errors2.ErrServiceDiscoveryNotMeeting
 		return
 	}
 
-	n, _ := rand.Int(rand.Reader, big.NewInt(10000))
-	renew := n.Int64()%111 == 0
-	xClient, err := getXClient(sd.BasePath, service, sd.Addr, renew)
+	xp, err := callProfile(service, serviceMethod, opts)
+	if err != nil {
+		return
+	}
+
+	l, err := pool.acquire(sd, service, xp)
 	if err != nil {
 		return
 	}
+	defer l.release()
 
-	ctx, cancel := context.WithTimeout(context.Background(), xClientDefaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, xp.timeout)
 	defer cancel()
+	resMeta, reqMeta := map[string]string{}, requestMetadata(ctx, xp)
+	canary := isCanary(ctx, xp.canary, args)
+	if canary {
+		reqMeta[canaryMetaKey] = "1"
+		canaryCounter(service).Inc(1)
+	}
+	ctx = context.WithValue(ctx, share.ReqMetaDataKey, reqMeta)
+	ctx = context.WithValue(ctx, share.ResMetaDataKey, resMeta)
 
-	err = xClient.Call(ctx, serviceMethod, args, &reply)
+	reply = &Reply{Service: service, Method: serviceMethod, Metadata: resMeta}
+	hedging := xp.hedge != nil && xp.retry.safe(l.gen, serviceMethod)
+	start := time.Now()
+	for {
+		reply.Attempts++
+		reply.Data = nil
+		if hedging {
+			err = hedge(ctx, l, xp, canary, service, serviceMethod, args, &reply.Data, call)
+		} else {
+			err = attempt(ctx, l, xp.retry, &callNode{canary: canary}, serviceMethod, args, &reply.Data, call)
+		}
+		if err == nil || reply.Attempts >= xp.retry.attempts || !xp.retry.retryable(err, l.gen, serviceMethod) ||
+			!xp.retry.wait(ctx, reply.Attempts) {
+			break
+		}
+		retryCounter(service).Inc(1)
+	}
+	reply.Elapsed = time.Since(start)
 	return
 }
 
-func getEtcdV3Discovery(basePath, service string, addr []string) (client.ServiceDiscovery, error) {
-	if v, ok := discoveryDict.Get(service); ok {
-		// log.Printf("loaded EtcdV3Discovery: %p, service: %s\n", v, service)
-		return v.(client.ServiceDiscovery), nil
+// attempt makes one attempt of a call within the attempt timeout of rp, if any, and records its outcome
+// in the breaker of the server selected.
+func attempt(ctx context.Context, l *lease, rp *retryPolicy, node *callNode, serviceMethod string, args, reply interface{},
+	call func(xc client.XClient, ctx context.Context, serviceMethod string, args, reply interface{}) error) error {
+	if rp.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rp.attemptTimeout)
+		defer cancel()
 	}
-
-	if v, loaded := discoveryDict.GetOrPutFunc(service, func(key string) (interface{}, error) {
-		if dis, err := client2.NewEtcdV3Discovery(basePath, key, addr, false, nil); err != nil {
-			return nil, fmt.Errorf("new etcd discovery error: %v", err)
-		} else {
-			return dis, nil
-		}
-	}); loaded {
-		// log.Printf("put EtcdV3Discovery haved old value: %p, service: %s\n", v, service)
-		return v.(client.ServiceDiscovery), nil
-	} else {
-		// log.Printf("new EtcdV3Discovery: %p, service: %s\n", v, service)
-		return v.(client.ServiceDiscovery), nil
+	start := time.Now()
+	err := call(l.XClient, context.WithValue(ctx, callNodeKey{}, node), serviceMethod, args, reply)
+	addr, guard, rejected := node.outcome()
+	if rejected && errors.Is(err, client.ErrXClientNoServer) {
+		err = client.ErrBreakerOpen
+	}
+	if guard != nil {
+		guard.record(addr, serviceMethod, err, time.Since(start))
 	}
+	return err
 }
 
-func getXClient(basePath, service string, addr []string, renew bool) (client.XClient, error) {
-	if v, ok := xClientDict.Get(service); ok {
-		// log.Printf("loaded xclient: %p, service: %s\n", v, service)
-		if renew {
-			if ov, loaded := xClientDict.Delete(service); loaded {
-				go func(s string, v interface{}) {
-					defer safe.Defer()
-					<-time.After(xClientDefaultTimeout)
-					err := v.(client.XClient).Close()
-					sender.Graceful(fmt.Sprintf("renew %s xclient: %v", s, err), true)
-				}(service, ov)
+// requestMetadata returns the metadata sent with a call, a new map so that the inbound one is never changed.
+func requestMetadata(ctx context.Context, xp *xClientProfile) map[string]string {
+	meta := make(map[string]string, len(xp.forward)+len(xp.metadata))
+	if inbound, ok := ctx.Value(share.ReqMetaDataKey).(map[string]string); ok {
+		for _, key := range xp.forward {
+			if v, ok := inbound[key]; ok {
+				meta[key] = v
 			}
-			if ov, loaded := discoveryDict.Delete(service); loaded {
-				go func(s string, v interface{}) {
-					defer safe.Defer()
-					<-time.After(xClientDefaultTimeout + time.Second)
-					v.(client.ServiceDiscovery).Close()
-					sender.Graceful(fmt.Sprintf("renew %s discovery", s), true)
-				}(service, ov)
-			}
-		} else {
-			return v.(client.XClient), nil
 		}
 	}
-
-	if v, loaded := xClientDict.GetOrPutFunc(service, func(key string) (interface{}, error) {
-		if dis, err := getEtcdV3Discovery(basePath, key, addr); err != nil {
-			return nil, err
-		} else {
-			return client.NewXClient(key, failMode, selectMode, dis, xClientOption), nil
-		}
-	}); loaded {
-		// log.Printf("put xclient have old value: %p, service: %s\n", v, service)
-		return v.(client.XClient), nil
-	} else {
-		// log.Printf("new xclient: %p, service: %s\n", v, service)
-		return v.(client.XClient), nil
+	for k, v := range xp.metadata {
+		meta[k] = v
 	}
+	return meta
 }
@@ -0,0 +1,61 @@
+// Command secret makes the key file and the enc:// values resolved by the config loader.
+//
+//	secret keygen > /etc/rpcx/secret.key
+//	secret encrypt -key /etc/rpcx/secret.key 'sdsd@df!LFD'
+//	echo -n 'sdsd@df!LFD' | secret encrypt -key /etc/rpcx/secret.key
+//
+// The key file is given to the service by global.secretKeyFile, or RPCX_GLOBAL_SECRETKEYFILE.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ZYallers/rpcx-framework/helper/secret"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "keygen":
+		key, err := secret.NewKey()
+		exitOnError(err)
+		fmt.Println(key)
+	case "encrypt":
+		fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+		keyFile := fs.String("key", os.Getenv("RPCX_GLOBAL_SECRETKEYFILE"), "key file made by keygen")
+		_ = fs.Parse(os.Args[2:])
+		key, err := secret.ReadKey(*keyFile)
+		exitOnError(err)
+		var value string
+		if fs.NArg() > 0 {
+			value = strings.Join(fs.Args(), " ")
+		} else {
+			b, err := ioutil.ReadAll(os.Stdin)
+			exitOnError(err)
+			value = strings.TrimRight(string(b), "\r\n")
+		}
+		enc, err := secret.Encrypt(key, value)
+		exitOnError(err)
+		fmt.Println(enc)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:\n  secret keygen\n  secret encrypt -key <key file> [value]")
+	os.Exit(2)
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
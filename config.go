@@ -5,33 +5,44 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ZYallers/golib/funcs/nets"
 	"github.com/ZYallers/rpcx-framework/consts"
+	"github.com/ZYallers/rpcx-framework/helper/redact"
+	"github.com/ZYallers/rpcx-framework/helper/schema"
+	"github.com/ZYallers/rpcx-framework/registry"
 	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/mitchellh/mapstructure"
+	"github.com/smallnest/rpcx/log"
 	"github.com/spf13/viper"
 )
 
 var (
-	serviceMode               string
-	serviceName               string
-	serviceAddr               string
-	serviceHostname           string
-	serviceLogDir             string
-	serviceVersion            string
-	serviceVersionKey         string
-	serviceErrorRobotToken    string
-	serviceGracefulRobotToken string
-	serviceSqlRobotToken      string
-	systemIP                  string
-	publicIP                  string
-	serviceDiscovery          *types.Discovery
+	serviceHostname string
+	systemIP        string
+	publicIP        string
+	configType      = "json"
+	configCache     atomic.Value
 )
 
+// settings is the typed config plus the values derived from it, it is swapped as a whole on every reload.
+type settings struct {
+	cfg       *types.Config
+	mode      string
+	profile   types.ModeConfig
+	addr      string
+	discovery *types.Discovery
+}
+
+// strictConfigKeys are the config blocks owned by the framework, unknown keys inside them are errors.
+var strictConfigKeys = []string{"service.log.", "service.access.", "service.redact.", "service.debug.", "service.etcd.", "service.registry.", "service.remote.", "service.metadata.", "service.client.", "global.modes."}
+
 func ReadInConfig(args ...string) {
-	relativePath, configName, configType := ".", "service", "json"
+	relativePath, configName := ".", "service"
 	argsLen := len(args)
 	if argsLen > 0 {
 		relativePath = args[0]
@@ -52,149 +63,205 @@ func ReadInConfig(args ...string) {
 	if err := viper.ReadInConfig(); err != nil {
 		panic(fmt.Errorf("read config file '%s/%s.%s' error: %s", configPath, configName, configType, err))
 	}
-}
-
-func ServiceMode() string {
-	if serviceMode == "" {
-		if modeKey := viper.GetString("global.modeKey"); modeKey == "" {
-			return consts.DevelopMode
-		} else {
-			if s := os.Getenv(modeKey); s == "" {
-				return consts.DevelopMode
-			} else {
-				serviceMode = s
-			}
-		}
+	if err := loadRemoteConfig(viper.GetViper()); err != nil {
+		panic(err)
 	}
-	return serviceMode
-}
-
-func ServiceName() string {
-	if serviceName == "" {
-		if s := viper.GetString("service.name"); s != "" {
-			serviceName = s
-		}
+	if err := mergeLayers(viper.GetViper()); err != nil {
+		panic(fmt.Errorf("config file '%s' %s", viper.ConfigFileUsed(), err))
 	}
-	return serviceName
-}
-
-func ServiceAddr() string {
-	if serviceAddr == "" {
-		if s := viper.GetString("service.addr"); s != "" {
-			if ip := SystemIP(); ip != "" {
-				serviceAddr = strings.Replace(s, "0.0.0.0", ip, 1)
-			}
-		}
+	s, unknown, err := loadSettings(viper.GetViper())
+	if err != nil {
+		panic(fmt.Errorf("config file '%s' %s", viper.ConfigFileUsed(), err))
+	}
+	for _, key := range unknown {
+		log.Warnf("config file '%s' has unknown key: %s", viper.ConfigFileUsed(), key)
 	}
-	return serviceAddr
+	storeSettings(s)
+	log.Infof("config file '%s' loaded:\n%s", viper.ConfigFileUsed(), dumpConfig(viper.GetViper(), s.cfg))
 }
 
-func ServiceHostname() string {
-	if serviceHostname == "" {
-		if s, _ := os.Hostname(); s != "" {
-			serviceHostname = strings.ToLower(s)
-		}
+// config returns the settings cached by the last (re)load, before ReadInConfig they are read on every call.
+func config() *settings {
+	if s, ok := configCache.Load().(*settings); ok {
+		return s
 	}
-	return serviceHostname
+	s, _, _ := loadSettings(viper.GetViper())
+	return s
 }
 
-func ServiceLogDir() string {
-	if serviceLogDir == "" {
-		if s := viper.GetString("service.logDir"); s != "" {
-			serviceLogDir = s
-		}
-	}
-	return serviceLogDir
+func storeSettings(s *settings) {
+	configCache.Store(s)
+	types.StoreConfig(s.cfg)
 }
 
-func ServiceVersion() string {
-	if serviceVersion == "" {
-		if s := viper.GetString("service.version"); s != "" {
-			serviceVersion = s
-		}
+// loadSettings decodes v into the typed config, fills the defaults and validates it.
+// The error lists every problem found, unknown keys outside the framework blocks are only returned.
+func loadSettings(v *viper.Viper) (*settings, []string, error) {
+	cfg := &types.Config{}
+	var md mapstructure.Metadata
+	var errs schema.Errors
+	if err := v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) { dc.Metadata = &md }); err != nil {
+		errs = append(errs, err.Error())
 	}
-	return serviceVersion
-}
+	schema.Defaults(cfg)
+	errs = append(errs, schema.Validate(cfg, "")...)
 
-func ServiceVersionKey() string {
-	if serviceVersionKey == "" {
-		if s := viper.GetString("service.versionKey"); s != "" {
-			serviceVersionKey = s
+	var unknown []string
+	for _, key := range md.Unused {
+		if strictConfigKey(key) {
+			errs = append(errs, key+" is unknown")
+		} else if strings.HasPrefix(key, "service.") {
+			unknown = append(unknown, key)
 		}
 	}
-	return serviceVersionKey
-}
 
-func ServiceErrorRobotToken() string {
-	if serviceErrorRobotToken == "" {
-		if s := viper.GetString("service.errorRobotToken"); s != "" {
-			serviceErrorRobotToken = s
-		}
+	s := &settings{cfg: cfg, mode: serviceMode(cfg)}
+	profile, ok := cfg.Mode(s.mode)
+	if !ok {
+		errs = append(errs, "global.modes."+s.mode+" is not defined")
 	}
-	return serviceErrorRobotToken
+	s.profile = profile
+	if ip := SystemIP(); ip != "" && cfg.Service.Addr != "" {
+		s.addr = strings.Replace(cfg.Service.Addr, "0.0.0.0", ip, 1)
+	}
+	var err error
+	if s.discovery, err = serviceDiscovery(cfg, s.mode); err != nil {
+		errs = append(errs, err.Error())
+	}
+	sort.Strings(unknown)
+	return s, unknown, errs.Err()
 }
 
-func ServiceGracefulRobotToken() string {
-	if serviceGracefulRobotToken == "" {
-		if s := viper.GetString("service.gracefulRobotToken"); s != "" {
-			serviceGracefulRobotToken = s
+func strictConfigKey(key string) bool {
+	for _, prefix := range strictConfigKeys {
+		if strings.HasPrefix(key, strings.ToLower(prefix)) {
+			return true
 		}
 	}
-	return serviceGracefulRobotToken
+	return false
 }
 
-func ServiceSqlRobotToken() string {
-	if serviceSqlRobotToken == "" {
-		if s := viper.GetString("service.sqlRobotToken"); s != "" {
-			serviceSqlRobotToken = s
+func serviceMode(c *types.Config) string {
+	if c.Global.Mode != "" {
+		return c.Global.Mode
+	}
+	if c.Global.ModeKey != "" {
+		if s := os.Getenv(c.Global.ModeKey); s != "" {
+			return s
 		}
 	}
-	return serviceSqlRobotToken
+	return consts.DevelopMode
 }
 
-func ServiceDiscovery() *types.Discovery {
-	if serviceDiscovery != nil {
-		return serviceDiscovery
-	}
-
-	mode := ServiceMode()
-	basePath := viper.GetString(fmt.Sprintf("service.etcd.%s.basePath", mode))
-	if basePath == "" {
-		return nil
+// serviceDiscovery returns the registry of mode, from service.registry or else service.etcd.
+// A mode without registry is an error, running without one takes an explicit static registry.
+func serviceDiscovery(c *types.Config, mode string) (*types.Discovery, error) {
+	key := "service.registry." + mode
+	rc, ok := c.Service.Registry[mode]
+	if !ok {
+		key = "service.etcd." + mode
+		if rc, ok = c.Service.Etcd[mode]; !ok {
+			return nil, fmt.Errorf("service.registry.%s is not defined, its type is static to run without registry", mode)
+		}
 	}
 
-	addr := viper.GetString(fmt.Sprintf("service.etcd.%s.addr", mode))
+	basePath, addr := rc.BasePath, rc.Addr
 	if len(addr) > 0 && addr[0:1] == "$" {
 		addr = os.Getenv(addr[1:])
 	}
-	if addr == "" {
-		return nil
-	}
-
-	if mode == consts.DevelopMode {
-		if hostname := ServiceHostname(); hostname != viper.GetString("global.server.development.hostname") {
-			if developerDockerHostname := os.Getenv("developer_docker_hostname"); developerDockerHostname != "" {
-				hostname = developerDockerHostname
-			}
-			basePath = strings.Replace(basePath, consts.DevelopMode, "developer@"+hostname, 1)
-			addr = strings.Replace(addr, "127.0.0.1", viper.GetString("global.server.development.ip"), 1)
+	switch rc.Type {
+	case registry.Static:
+	case registry.File:
+		if rc.File == "" {
+			return nil, fmt.Errorf("%s.file is required", key)
+		}
+	default:
+		if basePath == "" {
+			return nil, fmt.Errorf("%s.basePath is required", key)
+		}
+		if addr == "" {
+			return nil, fmt.Errorf("%s.addr is required", key)
 		}
 	}
 
-	interval := viper.GetInt64(fmt.Sprintf("service.etcd.%s.updateInterval", mode))
+	interval := rc.UpdateInterval
 	if interval <= 0 {
 		interval = 30
 	}
 
-	serviceDiscovery = &types.Discovery{
+	d := &types.Discovery{
+		Type:           rc.Type,
 		BasePath:       basePath,
-		Addr:           strings.Split(addr, ","),
 		UpdateInterval: time.Duration(interval) * time.Second,
+		Username:       rc.Username,
+		Password:       rc.Password,
+		Peers:          rc.Peers,
+		File:           rc.File,
+		Register:       rc.Register,
+	}
+	if ns := serviceNamespace(c, mode); ns != "" && basePath != "" {
+		profile, _ := c.Mode(mode)
+		if profile.Namespace.Fallback {
+			d.SharedBasePath = basePath
+		}
+		d.Namespace, d.BasePath = ns, namespaceBasePath(basePath, mode, ns)
+		if ip := c.Global.Server[mode].Ip; ip != "" {
+			addr = strings.Replace(addr, "127.0.0.1", ip, 1)
+		}
+	}
+	if addr != "" {
+		d.Addr = strings.Split(addr, ",")
 	}
+	return d, nil
+}
+
+// Config returns the typed config of the last (re)load.
+func Config() *types.Config { return config().cfg }
 
-	return serviceDiscovery
+func ServiceMode() string { return config().mode }
+
+// ServiceModeConfig returns the behaviour profile of the run mode.
+func ServiceModeConfig() types.ModeConfig { return config().profile }
+
+func ServiceName() string { return config().cfg.Service.Name }
+
+func ServiceAddr() string { return config().addr }
+
+func ServiceHostname() string {
+	if serviceHostname == "" {
+		if s, _ := os.Hostname(); s != "" {
+			serviceHostname = strings.ToLower(s)
+		}
+	}
+	return serviceHostname
 }
 
+func ServiceLogDir() string { return config().cfg.Service.LogDir }
+
+func ServiceLogConfig() *types.LogConfig { return config().cfg.Service.Log }
+
+func ServiceAccessConfig() *types.AccessConfig { return config().cfg.Service.Access }
+
+func ServiceRedactPolicy() *redact.Policy { return config().cfg.Service.Redact }
+
+func ServiceDebugConfig() *types.DebugConfig { return config().cfg.Service.Debug }
+
+func ServiceAdminToken() string { return config().cfg.Service.AdminToken }
+
+func ServiceVersion() string { return config().cfg.Service.Version }
+
+func ServiceVersionKey() string { return config().cfg.Service.VersionKey }
+
+func ServiceErrorRobotToken() string { return config().cfg.Service.ErrorRobotToken }
+
+func ServiceGracefulRobotToken() string { return config().cfg.Service.GracefulRobotToken }
+
+func ServiceSqlRobotToken() string { return config().cfg.Service.SqlRobotToken }
+
+func ServiceDiscovery() *types.Discovery { return config().discovery }
+
+func ServiceMetadataConfig() types.MetadataConfig { return config().cfg.Service.Metadata }
+
 func SystemIP() string {
 	if systemIP == "" {
 		if s := nets.SystemIP(); s != "" && s != "unknown" {
@@ -0,0 +1,57 @@
+package framework
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ZYallers/rpcx-framework/types"
+)
+
+var namespaceEnvRegex = regexp.MustCompile(`\{env:([^}]+)\}`)
+
+// serviceNamespace expands the namespace template of the mode profile, it is empty when the services are shared:
+// the template is empty, one of its env vars is unset, or the host is global.server.<mode>.hostname,
+// which runs the shared cluster.
+//
+// {hostname} is the developer_docker_hostname env var when set, so containers of a developer share a namespace.
+func serviceNamespace(c *types.Config, mode string) string {
+	profile, _ := c.Mode(mode)
+	ns := profile.Namespace.Name
+	if ns == "" {
+		return ""
+	}
+	hostname := ServiceHostname()
+	if server, ok := c.Global.Server[mode]; ok && server.Hostname != "" && hostname == server.Hostname {
+		return ""
+	}
+	if developerDockerHostname := os.Getenv("developer_docker_hostname"); developerDockerHostname != "" {
+		hostname = developerDockerHostname
+	}
+	missing := false
+	ns = namespaceEnvRegex.ReplaceAllStringFunc(ns, func(s string) string {
+		value := os.Getenv(namespaceEnvRegex.FindStringSubmatch(s)[1])
+		if value == "" {
+			missing = true
+		}
+		return value
+	})
+	if missing {
+		return ""
+	}
+	return strings.NewReplacer("{hostname}", hostname, "{mode}", mode, "/", "-").Replace(ns)
+}
+
+// namespaceBasePath replaces the mode segment of basePath by the namespace, or appends it when there is none:
+// /app/rpcx/development becomes /app/rpcx/developer@host.
+func namespaceBasePath(basePath, mode, ns string) string {
+	segments := strings.Split(basePath, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == mode {
+			segments[i] = ns
+			return strings.Join(segments, "/")
+		}
+	}
+	return path.Join(basePath, ns)
+}
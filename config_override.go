@@ -0,0 +1,193 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/helper/redact"
+	"github.com/ZYallers/rpcx-framework/helper/schema"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/log"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Every config key can be overridden without touching the config file, the precedence from high to low is:
+//
+//  1. command line flag:     --set service.addr=0.0.0.0:9000 (or --set=service.addr=...), repeatable
+//  2. environment variable:  RPCX_SERVICE_ADDR=0.0.0.0:9000, the key upper cased with dots replaced by underscores
+//  3. remote config:         the etcd keys of service.remote, see types.RemoteConfig
+//  4. config file:           service.json
+//  5. defaults:              the `default` tags of types.Config
+//
+// Keys are case insensitive, so RPCX_SERVICE_SIGNEXPIRE=120 overrides service.signExpire.
+// Underscores of env names are dots, so keys holding an underscore or a dash can only be set with --set.
+// Env vars that name no scalar key, like RPCX_SERVICE or RPCX_SOME_OTHER_TOOL, are skipped with a warning.
+// The run mode follows the same rules through global.mode, which takes precedence over global.modeKey.
+// Overrides are re-applied on every reload and the startup log dumps every key with its source.
+var ConfigEnvPrefix = "RPCX"
+
+const setFlag = "--set"
+
+// skippedEnv holds the env vars already warned about, the overrides are applied on every reload.
+var skippedEnv sync.Map
+
+type configOverride struct {
+	key    string
+	value  string
+	source string
+}
+
+// configOverrides returns the environment overrides followed by the flag overrides, so that flags win.
+func configOverrides() ([]configOverride, error) {
+	var overrides []configOverride
+	prefix := strings.ToUpper(ConfigEnvPrefix) + "_"
+	env := os.Environ()
+	sort.Strings(env)
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		if i <= len(prefix) || !strings.HasPrefix(kv, prefix) {
+			continue
+		}
+		key := strings.ToLower(strings.Replace(kv[len(prefix):i], "_", ".", -1))
+		overrides = append(overrides, configOverride{key: key, value: kv[i+1:], source: "env " + kv[:i]})
+	}
+
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		var kv string
+		switch {
+		case args[i] == setFlag || args[i] == "-set":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag %s needs a key=value argument", setFlag)
+			}
+			i++
+			kv = args[i]
+		case strings.HasPrefix(args[i], setFlag+"="), strings.HasPrefix(args[i], "-set="):
+			kv = args[i][strings.Index(args[i], "=")+1:]
+		default:
+			continue
+		}
+		j := strings.Index(kv, "=")
+		if j <= 0 {
+			return nil, fmt.Errorf("flag %s %q is not key=value", setFlag, kv)
+		}
+		overrides = append(overrides, configOverride{key: strings.ToLower(kv[:j]), value: kv[j+1:], source: "flag " + setFlag})
+	}
+	return overrides, nil
+}
+
+// applyOverrides merges the overrides into the config layer of v, so they survive as long as the file content.
+func applyOverrides(v *viper.Viper) error {
+	overrides, err := configOverrides()
+	if err != nil || len(overrides) == 0 {
+		return err
+	}
+	m := map[string]interface{}{}
+	for _, o := range overrides {
+		if strings.HasPrefix(o.source, "env ") && !isScalarKey(v, o.key) {
+			if _, warned := skippedEnv.LoadOrStore(o.source, true); !warned {
+				log.Warnf("%s is skipped, %s is not a config key", o.source, o.key)
+			}
+			continue
+		}
+		value, err := overrideValue(v.Get(o.key), o.value)
+		if err != nil {
+			return fmt.Errorf("%s %s: %s", o.source, o.key, err)
+		}
+		setPath(m, strings.Split(o.key, "."), value)
+	}
+	return v.MergeConfigMap(m)
+}
+
+// isScalarKey reports whether key is a leaf of types.Config or a value of v that is not a block.
+func isScalarKey(v *viper.Viper, key string) bool {
+	if _, ok := v.Get(key).(map[string]interface{}); ok {
+		return false
+	}
+	return schema.IsLeaf(&types.Config{}, key) || v.IsSet(key)
+}
+
+// overrideValue converts s to the type of the value it replaces, viper refuses to merge values of another type.
+func overrideValue(old interface{}, s string) (interface{}, error) {
+	switch old.(type) {
+	case bool:
+		return cast.ToBoolE(s)
+	case int:
+		return cast.ToIntE(s)
+	case int64:
+		return cast.ToInt64E(s)
+	case float64:
+		return cast.ToFloat64E(s)
+	case []interface{}:
+		var list []interface{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		return nil, errors.New("can not override a config block, override its keys instead")
+	}
+	return s, nil
+}
+
+func setPath(m map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		sub, ok := m[key].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			m[key] = sub
+		}
+		m = sub
+	}
+	m[path[len(path)-1]] = value
+}
+
+// dumpConfig lists every key of c with its value and source, secrets are masked.
+func dumpConfig(v *viper.Viper, c *types.Config) string {
+	sources := map[string]string{}
+	if _, keys, err := remoteConfig(v); err == nil {
+		for _, key := range keys {
+			sources[key] = "remote"
+		}
+	}
+	if overrides, err := configOverrides(); err == nil {
+		for _, o := range overrides {
+			sources[o.key] = o.source
+		}
+	}
+	var b strings.Builder
+	schema.Walk(c, func(path string, field reflect.StructField, fv reflect.Value) {
+		key := strings.ToLower(path)
+		source, ok := sources[key]
+		if !ok {
+			if source = "default"; v.IsSet(key) {
+				source = "file"
+			}
+		}
+		value := fmt.Sprint(fv.Interface())
+		if isSecretKey(key) {
+			source += ", secret"
+		}
+		if isSecretKey(key) || field.Tag.Get(redact.TagName) == "true" || redact.IsKey(field.Name) || redact.IsKey(fieldKey(path)) {
+			if value != "" {
+				value = redact.DefaultMask
+			}
+		} else {
+			value = redact.String(value)
+		}
+		fmt.Fprintf(&b, "  %s = %s (%s)\n", path, value, source)
+	})
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func fieldKey(path string) string {
+	return path[strings.LastIndex(path, ".")+1:]
+}
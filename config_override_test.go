@@ -0,0 +1,35 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestApplyOverridesSkipsUnknownEnv(t *testing.T) {
+	t.Setenv("RPCX_SERVICE", "x")
+	t.Setenv("RPCX_SOME_OTHER_TOOL", "1")
+	t.Setenv("RPCX_SERVICE_SIGNEXPIRE", "120")
+	t.Setenv("RPCX_SERVICE_ACCESS_SAMPLE", "0.5")
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.ReadConfig(strings.NewReader(`{"service": {"name": "overridetest", "signExpire": 10}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyOverrides(v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetInt("service.signExpire"); got != 120 {
+		t.Fatalf("service.signExpire = %d, want 120", got)
+	}
+	if got := v.GetString("service.access.sample"); got != "0.5" {
+		t.Fatalf("service.access.sample = %q, want 0.5", got)
+	}
+	if got := v.GetString("service.name"); got != "overridetest" {
+		t.Fatalf("service.name = %q, want overridetest", got)
+	}
+	if v.IsSet("some") {
+		t.Fatal("RPCX_SOME_OTHER_TOOL was merged")
+	}
+}
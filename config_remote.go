@@ -0,0 +1,298 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/helper/schema"
+	"github.com/ZYallers/rpcx-framework/helper/sender"
+	"github.com/ZYallers/rpcx-framework/registry"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rpcxio/libkv"
+	"github.com/rpcxio/libkv/store"
+	estore "github.com/rpcxio/rpcx-etcd/store"
+	"github.com/rpcxio/rpcx-etcd/store/etcdv3"
+	"github.com/smallnest/rpcx/log"
+	"github.com/spf13/viper"
+)
+
+const remoteRetryDelay = 5 * time.Second
+
+// remoteSource is where the remote config of the service lives, derived from the service.remote block.
+type remoteSource struct {
+	dir      string
+	addrs    []string
+	snapshot string
+	timeout  time.Duration
+	failing  bool // the watch is lost, alerted once until it is back
+}
+
+var remote = struct {
+	sync.RWMutex
+	source *remoteSource
+	values map[string]string // raw etcd values by key relative to source.dir
+}{}
+
+func init() {
+	etcdv3.Register()
+}
+
+// newRemoteSource returns nil when the remote config is disabled in v.
+func newRemoteSource(v *viper.Viper) *remoteSource {
+	c := &types.Config{}
+	_ = v.Unmarshal(c)
+	schema.Defaults(c)
+	rc := c.Service.Remote
+	if rc == nil || !rc.Enable || c.Service.Name == "" {
+		return nil
+	}
+	mode := serviceMode(c)
+	src := &remoteSource{
+		dir:      path.Join("/", rc.Prefix, c.Service.Name, mode) + "/",
+		snapshot: rc.Snapshot,
+		timeout:  time.Duration(rc.Timeout) * time.Second,
+	}
+	if rc.Addr != "" {
+		src.addrs = strings.Split(rc.Addr, ",")
+	} else if discovery, _ := serviceDiscovery(c, mode); discovery != nil && discovery.Type == registry.Etcd {
+		src.addrs = discovery.Addr
+	}
+	if src.snapshot == "" && c.Service.LogDir != "" {
+		src.snapshot = filepath.Join(c.Service.LogDir, c.Service.Name+".remote.json")
+	}
+	return src
+}
+
+// loadRemoteConfig fetches the remote config enabled in v, falling back to the local snapshot of the last fetch.
+func loadRemoteConfig(v *viper.Viper) error {
+	if err := applyOverrides(v); err != nil {
+		return err
+	}
+	src := newRemoteSource(v)
+	if src == nil {
+		return nil
+	}
+	values, err := src.fetch()
+	if err != nil {
+		log.Warnf("remote config %s is unavailable, fallback to snapshot %s: %v", src.dir, src.snapshot, err)
+		if values, err = src.readSnapshot(); err != nil {
+			return fmt.Errorf("remote config %s and its snapshot are unavailable: %v", src.dir, err)
+		}
+	} else if err := src.writeSnapshot(values); err != nil {
+		log.Warnf("remote config snapshot %s write error: %v", src.snapshot, err)
+	}
+	remote.Lock()
+	remote.source, remote.values = src, values
+	remote.Unlock()
+	return nil
+}
+
+// mergeLayers merges the remote config and then the overrides over the file content of v, then resolves the secrets.
+func mergeLayers(v *viper.Viper) error {
+	m, _, err := remoteConfig(v)
+	if err != nil {
+		return err
+	}
+	if len(m) > 0 {
+		if err := v.MergeConfigMap(m); err != nil {
+			return err
+		}
+	}
+	if err := applyOverrides(v); err != nil {
+		return err
+	}
+	return resolveSecrets(v)
+}
+
+// remoteConfig builds the nested config map of the remote values, typed like the values of v they replace,
+// keys lists every lower cased config key set remotely.
+func remoteConfig(v *viper.Viper) (m map[string]interface{}, keys []string, err error) {
+	remote.RLock()
+	defer remote.RUnlock()
+	m = map[string]interface{}{}
+	for rel, raw := range remote.values {
+		key := strings.ToLower(strings.Trim(strings.Replace(rel, "/", ".", -1), "."))
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		if key == "" {
+			root, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("remote config %s is not an object", remote.source.dir)
+			}
+			for k, sub := range root {
+				m[strings.ToLower(k)] = sub
+				keys = append(keys, flattenKeys(strings.ToLower(k), sub)...)
+			}
+			continue
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			s := strings.TrimSpace(raw)
+			if str, ok := value.(string); ok {
+				s = str
+			}
+			if value, err = overrideValue(v.Get(key), s); err != nil {
+				return nil, nil, fmt.Errorf("remote config %s%s: %s", remote.source.dir, rel, err)
+			}
+		}
+		setPath(m, strings.Split(key, "."), value)
+		keys = append(keys, flattenKeys(key, value)...)
+	}
+	return m, keys, nil
+}
+
+func flattenKeys(key string, value interface{}) []string {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return []string{key}
+	}
+	var keys []string
+	for k, sub := range m {
+		keys = append(keys, flattenKeys(key+"."+strings.ToLower(k), sub)...)
+	}
+	return keys
+}
+
+func (src *remoteSource) store() (store.Store, error) {
+	if len(src.addrs) == 0 {
+		return nil, errors.New("etcd address is empty")
+	}
+	kv, err := libkv.NewStore(estore.ETCDV3, src.addrs, &store.Config{ConnectionTimeout: src.timeout})
+	if err != nil {
+		return nil, err
+	}
+	if ev3, ok := kv.(*etcdv3.EtcdV3); ok {
+		ev3.AllowKeyNotFound = true
+	}
+	return kv, nil
+}
+
+func (src *remoteSource) fetch() (map[string]string, error) {
+	kv, err := src.store()
+	if err != nil {
+		return nil, err
+	}
+	defer kv.Close()
+	pairs, err := kv.List(src.dir)
+	if err != nil && err != store.ErrKeyNotFound {
+		return nil, err
+	}
+	return src.values(pairs), nil
+}
+
+func (src *remoteSource) values(pairs []*store.KVPair) map[string]string {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		values[strings.TrimPrefix(pair.Key, src.dir)] = string(pair.Value)
+	}
+	return values
+}
+
+func (src *remoteSource) readSnapshot() (map[string]string, error) {
+	if src.snapshot == "" {
+		return nil, errors.New("snapshot file is not set")
+	}
+	b, err := ioutil.ReadFile(src.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	return values, json.Unmarshal(b, &values)
+}
+
+func (src *remoteSource) writeSnapshot(values map[string]string) error {
+	if src.snapshot == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	if old, _ := ioutil.ReadFile(src.snapshot); bytes.Equal(old, b) {
+		return nil
+	}
+	tmp := src.snapshot + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, src.snapshot)
+}
+
+// watchRemoteConfig reloads the config whenever a key under the remote dir changes, reconnecting when etcd is lost.
+func watchRemoteConfig() {
+	remote.RLock()
+	src := remote.source
+	remote.RUnlock()
+	if src == nil {
+		return
+	}
+	go func() {
+		defer safe.Defer()
+		for {
+			src.watch()
+			time.Sleep(remoteRetryDelay)
+		}
+	}()
+}
+
+func (src *remoteSource) watch() {
+	kv, err := src.store()
+	if err != nil {
+		src.fail(err)
+		return
+	}
+	defer kv.Close()
+	ch, err := kv.WatchTree(src.dir, nil)
+	if err != nil {
+		src.fail(err)
+		return
+	}
+	for pairs := range ch {
+		// the first pairs are listed when the watch starts
+		if src.failing {
+			src.failing = false
+			log.Infof("remote config %s watch is back", src.dir)
+		}
+		values := src.values(pairs)
+		remote.Lock()
+		changed := !reflect.DeepEqual(remote.values, values)
+		if changed {
+			remote.values = values
+		}
+		remote.Unlock()
+		if !changed {
+			continue
+		}
+		if err := src.writeSnapshot(values); err != nil {
+			log.Warnf("remote config snapshot %s write error: %v", src.snapshot, err)
+		}
+		if err := ReloadConfig(); err != nil {
+			sender.Error(fmt.Sprintf("remote config reload rejected: %s", err), "", true, "info")
+		}
+	}
+	src.fail(errors.New("etcd is unavailable"))
+}
+
+// fail alerts the loss of the watch once, the reconnections until it is back are only logged.
+func (src *remoteSource) fail(err error) {
+	if src.failing {
+		log.Infof("remote config %s watch error: %v", src.dir, err)
+		return
+	}
+	src.failing = true
+	log.Errorf("remote config %s watch error: %v", src.dir, err)
+}
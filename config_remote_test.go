@@ -0,0 +1,42 @@
+package framework
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func newRemoteViper(t *testing.T, addr string) *viper.Viper {
+	snapshot, _ := json.Marshal(filepath.Join(t.TempDir(), "remote.json"))
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.ReadConfig(strings.NewReader(`{"global": {"mode": "development"}, "service": {"name": "remotetest",
+		"signExpire": 10, "remote": {"enable": true, "addr": "` + addr + `", "timeout": 1, "snapshot": ` + string(snapshot) + `}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRemoteConfigSnapshotFallback(t *testing.T) {
+	v := newRemoteViper(t, "127.0.0.1:1")
+	src := newRemoteSource(v)
+	if err := src.writeSnapshot(map[string]string{"service/signExpire": "30"}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(src.snapshot); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("snapshot mode = %v, %v, want 0600", fi.Mode().Perm(), err)
+	}
+	if err := loadRemoteConfig(v); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeLayers(v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetInt("service.signExpire"); got != 30 {
+		t.Fatalf("service.signExpire = %d, want 30 from the snapshot", got)
+	}
+}
@@ -0,0 +1,47 @@
+package framework
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/ZYallers/rpcx-framework/helper/schema"
+	"github.com/ZYallers/rpcx-framework/helper/secret"
+	"github.com/spf13/viper"
+)
+
+// secretKeys holds the lower cased keys whose value was resolved from a secret, they are masked in the config dump.
+var secretKeys atomic.Value
+
+// resolveSecrets replaces every secret://, env:// and enc:// value of v with the value it refers to,
+// enc:// values are decrypted with the key file of global.secretKeyFile.
+func resolveSecrets(v *viper.Viper) error {
+	keyFile := v.GetString("global.secretKeyFile")
+	m, keys := map[string]interface{}{}, map[string]bool{}
+	var errs schema.Errors
+	for _, key := range v.AllKeys() {
+		s, ok := v.Get(key).(string)
+		if !ok || !secret.IsRef(s) {
+			continue
+		}
+		value, err := secret.Resolve(s, keyFile)
+		if err != nil {
+			errs = append(errs, key+" secret can not be resolved: "+err.Error())
+			continue
+		}
+		setPath(m, strings.Split(key, "."), value)
+		keys[key] = true
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+	secretKeys.Store(keys)
+	if len(m) == 0 {
+		return nil
+	}
+	return v.MergeConfigMap(m)
+}
+
+func isSecretKey(key string) bool {
+	keys, _ := secretKeys.Load().(map[string]bool)
+	return keys[key]
+}
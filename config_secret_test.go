@@ -0,0 +1,74 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZYallers/rpcx-framework/helper/secret"
+	"github.com/spf13/viper"
+)
+
+func newSecretViper(t *testing.T, config string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestResolveSecretsNested(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := secret.NewKey()
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, _ := secret.ReadKey(keyFile)
+	enc, _ := secret.Encrypt(k, "from-enc")
+	t.Setenv("SECRET_TEST_TOKEN", "from-env")
+
+	v := newSecretViper(t, `{"global": {"secretKeyFile": "`+keyFile+`"}, "service": {"name": "secrettest",
+		"redis": {"main": {"password": "secret://`+file+`", "addr": "127.0.0.1:6379"}},
+		"robot": {"token": "env://SECRET_TEST_TOKEN"}, "signSecret": "`+enc+`"}}`)
+	if err := resolveSecrets(v); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]struct {
+		value  string
+		secret bool
+	}{
+		"service.redis.main.password": {"from-file", true},
+		"service.robot.token":         {"from-env", true},
+		"service.signsecret":          {"from-enc", true},
+		"service.redis.main.addr":     {"127.0.0.1:6379", false},
+		"service.name":                {"secrettest", false},
+	} {
+		if got := v.GetString(key); got != want.value {
+			t.Errorf("%s = %q, want %q", key, got, want.value)
+		}
+		if got := isSecretKey(key); got != want.secret {
+			t.Errorf("isSecretKey(%s) = %v, want %v", key, got, want.secret)
+		}
+	}
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	v := newSecretViper(t, `{"service": {"redis": {"main": {"password": "env://SECRET_TEST_MISSING"}},
+		"signSecret": "enc://AAAA"}}`)
+	err := resolveSecrets(v)
+	if err == nil {
+		t.Fatal("resolveSecrets has no error")
+	}
+	for _, key := range []string{"service.redis.main.password", "service.signsecret"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not name %s", err, key)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package framework
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/helper/sender"
+	"github.com/fsnotify/fsnotify"
+	"github.com/smallnest/rpcx/log"
+	"github.com/spf13/viper"
+)
+
+const configReloadDelay = 500 * time.Millisecond
+
+var (
+	reloadLock  sync.Mutex
+	subscribers = struct {
+		sync.RWMutex
+		m map[string][]func(old, new interface{})
+	}{m: map[string][]func(old, new interface{}){}}
+)
+
+// OnChange calls fn with the old and new value of key after every reload that changes it,
+// key is a dotted viper path such as "service.signExpire" or "service.debug".
+func OnChange(key string, fn func(old, new interface{})) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	key = strings.ToLower(key)
+	subscribers.m[key] = append(subscribers.m[key], fn)
+}
+
+// WatchConfig reloads the config whenever the file read by ReadInConfig or the remote config changes,
+// a reload failing validation is rejected and reported through the sender while the previous config stays in use.
+func WatchConfig() error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return errors.New("config file is not read yet")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// watch the directory, editors and config maps replace the file rather than writing it
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	go func() {
+		defer safe.Defer()
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(file) || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(configReloadDelay, func() {
+					if err := ReloadConfig(); err != nil {
+						sender.Error(fmt.Sprintf("config reload rejected: %s", err), "", true, "info")
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("config watcher error: %v", err)
+			}
+		}
+	}()
+	watchRemoteConfig()
+	return nil
+}
+
+// ReloadConfig re-reads the config file, merges the remote config and the overrides over it and validates the result, then swaps it in and notifies the OnChange subscribers.
+func ReloadConfig() error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	b, err := ioutil.ReadFile(viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+	v := viper.New()
+	v.SetConfigType(configType)
+	if err := v.ReadConfig(bytes.NewReader(b)); err != nil {
+		return err
+	}
+	if err := mergeLayers(v); err != nil {
+		return err
+	}
+	s, _, err := loadSettings(v)
+	if err != nil {
+		return err
+	}
+
+	subscribers.RLock()
+	defer subscribers.RUnlock()
+	old := make(map[string]interface{}, len(subscribers.m))
+	for key := range subscribers.m {
+		old[key] = viper.Get(key)
+	}
+	if err := viper.ReadConfig(bytes.NewReader(b)); err != nil {
+		return err
+	}
+	_ = mergeLayers(viper.GetViper())
+	storeSettings(s)
+	log.Infof("config %s reloaded", viper.ConfigFileUsed())
+
+	for key, fns := range subscribers.m {
+		if value := viper.Get(key); !reflect.DeepEqual(old[key], value) {
+			for _, fn := range fns {
+				notifyChange(fn, old[key], value)
+			}
+		}
+	}
+	return nil
+}
+
+func notifyChange(fn func(old, new interface{}), old, new interface{}) {
+	defer safe.Defer()
+	fn(old, new)
+}
@@ -1,5 +1,6 @@
 package consts
 
+// Built-in run modes, more are defined with their profile in global.modes.
 const (
 	DevelopMode = "development"
 	GrayMode    = "gray"
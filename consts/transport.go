@@ -0,0 +1,7 @@
+package consts
+
+const (
+	TCPTransport     = "tcp"
+	HTTPTransport    = "http"
+	JSONRPCTransport = "jsonrpc"
+)
@@ -9,4 +9,5 @@ var (
 	ErrNeedLogin                  = errors.New("please login first")
 	ErrOperationFailed            = errors.New("the operation failed. Please try again later")
 	ErrServiceDiscoveryNotMeeting = errors.New("service discovery not meeting requirements")
+	ErrAdminToken                 = errors.New("invalid admin token")
 )
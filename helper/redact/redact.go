@@ -0,0 +1,184 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	DefaultMask = "***"
+	// TagName marks struct fields to redact, e.g. `redact:"true"`.
+	TagName = "redact"
+)
+
+var DefaultKeys = []string{"password", "passwd", "sess_token", "admin_token", "sign", "signSecret", "secret", "token"}
+
+// Policy is the `service.redact` config block.
+type Policy struct {
+	Keys     []string `mapstructure:"keys"`                       // arg, map and field names whose values are masked, case insensitive
+	Patterns []string `mapstructure:"patterns" validate:"regexp"` // regexes whose matches are masked in free text
+	Mask     string   `mapstructure:"mask"`
+}
+
+type compiled struct {
+	mask     string
+	keys     map[string]bool
+	keyRegex *regexp.Regexp
+	patterns []*regexp.Regexp
+}
+
+var current atomic.Value
+
+func init() {
+	_ = SetPolicy(&Policy{Keys: DefaultKeys})
+}
+
+// SetPolicy compiles and installs p as the process wide redaction policy.
+func SetPolicy(p *Policy) error {
+	c := &compiled{mask: p.Mask, keys: map[string]bool{}}
+	if c.mask == "" {
+		c.mask = DefaultMask
+	}
+	quoted := make([]string, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		if key = strings.TrimSpace(key); key != "" {
+			c.keys[strings.ToLower(key)] = true
+			quoted = append(quoted, regexp.QuoteMeta(key))
+		}
+	}
+	if len(quoted) > 0 {
+		// key=value, key:value, "key":"value" and key: value as printed by %v, json and query strings,
+		// a quoted value is masked whole, spaces and escaped quotes included
+		c.keyRegex = regexp.MustCompile(`(?i)(\b(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*)(?:(")(?:[^"\\]|\\.)*"|[^"&,\s}\]]+)`)
+	}
+	for _, pattern := range p.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	current.Store(c)
+	return nil
+}
+
+func policy() *compiled { return current.Load().(*compiled) }
+
+// IsKey reports whether values under the key name are masked.
+func IsKey(key string) bool { return policy().keys[strings.ToLower(key)] }
+
+// String masks the values of sensitive keys and every pattern match in s.
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	c := policy()
+	if c.keyRegex != nil {
+		s = c.keyRegex.ReplaceAllString(s, "${1}${2}"+c.mask+"${2}")
+	}
+	for _, re := range c.patterns {
+		s = re.ReplaceAllString(s, c.mask)
+	}
+	return s
+}
+
+// Map returns a copy of m with sensitive values masked, nested values are redacted by Value.
+func Map(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := policy()
+	cp := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if c.keys[strings.ToLower(k)] {
+			cp[k] = c.mask
+		} else {
+			cp[k] = Value(v)
+		}
+	}
+	return cp
+}
+
+// Value returns a redacted copy of v, structs are converted to maps keyed by their json names
+// and fields tagged `redact:"true"` are masked.
+func Value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return String(val)
+	case []byte:
+		return String(string(val))
+	case map[string]interface{}:
+		return Map(val)
+	}
+	return value(reflect.ValueOf(v), policy())
+}
+
+func value(rv reflect.Value, c *compiled) interface{} {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return value(rv.Elem(), c)
+	case reflect.String:
+		return String(rv.String())
+	case reflect.Map:
+		// the keys of the maps decoded from yaml are interfaces
+		cp := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			if c.keys[strings.ToLower(k)] {
+				cp[k] = c.mask
+			} else {
+				cp[k] = value(iter.Value(), c)
+			}
+		}
+		return cp
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return String(string(rv.Bytes()))
+		}
+		cp := make([]interface{}, rv.Len())
+		for i := range cp {
+			cp[i] = value(rv.Index(i), c)
+		}
+		return cp
+	case reflect.Struct:
+		if rv.CanInterface() {
+			if _, ok := rv.Interface().(json.Marshaler); ok {
+				return rv.Interface()
+			}
+		}
+		rt := rv.Type()
+		cp := make(map[string]interface{}, rt.NumField())
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			if field.Tag.Get(TagName) == "true" || c.keys[strings.ToLower(name)] {
+				cp[name] = c.mask
+			} else {
+				cp[name] = value(rv.Field(i), c)
+			}
+		}
+		return cp
+	}
+	if rv.IsValid() && rv.CanInterface() {
+		return rv.Interface()
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`{"sess_token":"t0k en","id":1}`, `{"sess_token":"***","id":1}`},
+		{`password = "my secret pass"`, `password = "***"`},
+		{`{"password":"a \"quoted\" pass","name":"bob"}`, `{"password":"***","name":"bob"}`},
+		{`{"sign": ""}`, `{"sign": "***"}`},
+		{`?token=abc&id=1`, `?token=***&id=1`},
+		{`map[password:secret name:bob]`, `map[password:*** name:bob]`},
+		{`sess_token: t0k, id: 1`, `sess_token: ***, id: 1`},
+		{`no secrets here`, `no secrets here`},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValueInterfaceKeys(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"password": "secret",
+		"nested":   map[interface{}]interface{}{"token": "t0k", 1: "one"},
+	}
+	want := map[string]interface{}{
+		"password": DefaultMask,
+		"nested":   map[string]interface{}{"token": DefaultMask, "1": "one"},
+	}
+	if got := Value(in); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Value() = %#v, want %#v", got, want)
+	}
+}
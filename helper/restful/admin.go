@@ -0,0 +1,59 @@
+package restful
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/ZYallers/golib/funcs/conv"
+
+	"github.com/ZYallers/rpcx-framework/errors"
+	"github.com/ZYallers/rpcx-framework/types"
+)
+
+const (
+	adminPrefix   = "admin"
+	adminTokenKey = "admin_token"
+)
+
+// AdminFunc handles an admin call, its result is the call reply.
+type AdminFunc func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// RegisterAdminFunc registers fn as the "admin<Name>" function of the service,
+// callers must pass `service.adminToken` as the admin_token arg. Nothing is registered without an admin token.
+func RegisterAdminFunc(rs *types.Rpc, name string, fn AdminFunc) error {
+	if rs.AdminToken == "" {
+		return nil
+	}
+	return rs.Server.RegisterFunctionName(rs.Name, adminPrefix+name, func(ctx context.Context,
+		args map[string]interface{}, reply *interface{}) (err error) {
+		defer func() { types.AccessFromContext(ctx).Failed(err) }()
+		token, _ := args[adminTokenKey].(string)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(rs.AdminToken)) != 1 {
+			return errors.ErrAdminToken
+		}
+		result, err := fn(ctx, args)
+		if err != nil {
+			return err
+		}
+		*reply = result
+		return nil
+	}, metadata(rs))
+}
+
+// registerLogLevelFunc registers adminLogLevel, it changes the level of the logger named by the `name` arg,
+// or of every logger, to `level` for `revert` seconds; `reset` restores the initial levels.
+func registerLogLevelFunc(rs *types.Rpc) error {
+	return RegisterAdminFunc(rs, "LogLevel", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		name, _ := args["name"].(string)
+		if reset, _ := args["reset"].(bool); reset {
+			types.ResetLogLevel(name)
+		} else if level, _ := args["level"].(string); level != "" {
+			revert := time.Duration(conv.ToInt64(args["revert"])) * time.Second
+			if err := types.SetLogLevel(name, level, revert); err != nil {
+				return nil, err
+			}
+		}
+		return types.LogLevels(), nil
+	})
+}
@@ -3,18 +3,42 @@ package restful
 import (
 	"context"
 	"reflect"
+	"sort"
 
 	"github.com/ZYallers/rpcx-framework/errors"
 	"github.com/ZYallers/rpcx-framework/types"
 	"github.com/syyongx/php2go"
 )
 
-const stateActive = "state=active"
+const (
+	stateActive = "state=active"
+	healthFunc  = "health"
+)
 
 func RegisterFuncName(rs *types.Rpc, services types.Restful) error {
+	if rs.Metadata != nil {
+		var versions []string
+		idempotent := []string{healthFunc}
+		for path, handlers := range services {
+			all := true
+			for _, handler := range handlers {
+				versions = append(versions, handler.Version)
+				all = all && handler.Idempotent
+			}
+			if all {
+				idempotent = append(idempotent, path)
+			}
+		}
+		rs.Metadata.SetHandlers(versions...)
+		sort.Strings(idempotent)
+		rs.Metadata.Idempotent = idempotent
+	}
 	if err := registerHealthFunc(rs); err != nil {
 		return err
 	}
+	if err := registerLogLevelFunc(rs); err != nil {
+		return err
+	}
 	if len(services) > 0 {
 		if err := registerServiceMethod(rs, &services); err != nil {
 			return err
@@ -23,17 +47,25 @@ func RegisterFuncName(rs *types.Rpc, services types.Restful) error {
 	return nil
 }
 
+// metadata returns the registry metadata of rs, only the state when it has none.
+func metadata(rs *types.Rpc) string {
+	if rs.Metadata == nil {
+		return stateActive
+	}
+	return rs.Metadata.String()
+}
+
 func registerHealthFunc(rs *types.Rpc) error {
-	return rs.Server.RegisterFunctionName(rs.Name, "health", func(ctx context.Context,
+	return rs.Server.RegisterFunctionName(rs.Name, healthFunc, func(ctx context.Context,
 		args map[string]interface{}, reply *interface{}) error {
 		*reply = "ok"
 		return nil
-	}, stateActive)
+	}, metadata(rs))
 }
 
 func registerServiceMethod(rs *types.Rpc, services *types.Restful) error {
 	for path, handlers := range *services {
-		if err := rs.Server.RegisterFunctionName(rs.Name, path, dispatchHandler(rs, handlers), stateActive); err != nil {
+		if err := rs.Server.RegisterFunctionName(rs.Name, path, dispatchHandler(rs, handlers), metadata(rs)); err != nil {
 			return err
 		}
 	}
@@ -41,7 +73,9 @@ func registerServiceMethod(rs *types.Rpc, services *types.Restful) error {
 }
 
 func dispatchHandler(rs *types.Rpc, handlers []types.RestHandler) func(ctx context.Context, args map[string]interface{}, reply *interface{}) error {
-	return func(ctx context.Context, args map[string]interface{}, reply *interface{}) error {
+	return func(ctx context.Context, args map[string]interface{}, reply *interface{}) (err error) {
+		access := types.AccessFromContext(ctx)
+		defer func() { access.Failed(err) }()
 		argsVersion := rs.Version
 		if ver, ok := args[rs.VersionKey].(string); ok && ver != "" {
 			argsVersion = ver
@@ -54,11 +88,20 @@ func dispatchHandler(rs *types.Rpc, handlers []types.RestHandler) func(ctx conte
 			ptr.Elem().Set(v.Elem())
 			sv := ptr.Interface().(types.IService)
 			sv.Construct(rs, ctx, args, reply)
-			if handler.Signed && !sv.SignCheck() {
-				return errors.ErrSignature
+			access.SetHandler(handler.Version, args)
+			if handler.Signed {
+				ok := sv.SignCheck()
+				access.SetSigned(ok)
+				if !ok {
+					return errors.ErrSignature
+				}
 			}
-			if handler.Logged && !sv.LoginCheck() {
-				return errors.ErrNeedLogin
+			if handler.Logged {
+				ok := sv.LoginCheck()
+				access.SetLogged(ok)
+				if !ok {
+					return errors.ErrNeedLogin
+				}
 			}
 			result := ptr.MethodByName(handler.Method).Call(nil)
 			if result[0].IsNil() {
@@ -42,12 +42,13 @@ func GetServices() types.Restful {
 			}
 
 			resHandler := types.RestHandler{
-				Path:    path,
-				Service: service,
-				Method:  methodName,
-				Version: fieldTagVal.Get("ver"),
-				Signed:  fieldTagVal.Get("sign") == "on",
-				Logged:  fieldTagVal.Get("login") == "on",
+				Path:       path,
+				Service:    service,
+				Method:     methodName,
+				Version:    fieldTagVal.Get("ver"),
+				Signed:     fieldTagVal.Get("sign") == "on",
+				Logged:     fieldTagVal.Get("login") == "on",
+				Idempotent: fieldTagVal.Get("idempotent") == "on",
 			}
 			if sortStr := fieldTagVal.Get("sort"); sortStr != "" {
 				if sortInt, err := strconv.Atoi(sortStr); err != nil {
@@ -0,0 +1,319 @@
+package schema
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultTag sets the value of a zero field, slices take comma separated values.
+	DefaultTag = "default"
+	// ValidateTag lists the comma separated rules of a field:
+	// required, min=n, max=n (value of numbers, length of strings, slices and maps),
+	// oneof=a b c, hostport and regexp (every string must compile).
+	ValidateTag = "validate"
+	nameTag     = "mapstructure"
+)
+
+// Errors aggregates every problem found in a config.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid config:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Err returns nil when there are no errors, so the result can be compared with nil.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Defaults fills the zero fields of the struct pointed by ptr with their default tag, recursively.
+func Defaults(ptr interface{}) {
+	defaults(reflect.ValueOf(ptr))
+}
+
+// Validate checks the validate tags of the struct pointed by ptr, field paths in errors start with prefix.
+func Validate(ptr interface{}, prefix string) Errors {
+	var errs Errors
+	validate(reflect.ValueOf(ptr), prefix, &errs)
+	sort.Strings(errs)
+	return errs
+}
+
+func defaults(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if !rv.IsNil() {
+			defaults(rv.Elem())
+		}
+	case reflect.Map:
+		if rv.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			cp := reflect.New(rv.Type().Elem()).Elem()
+			cp.Set(iter.Value())
+			defaults(cp)
+			rv.SetMapIndex(iter.Key(), cp)
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field, fv := rt.Field(i), rv.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if def, ok := field.Tag.Lookup(DefaultTag); ok && fv.IsZero() {
+				_ = setString(fv, def)
+			}
+			defaults(fv)
+		}
+	}
+}
+
+func validate(rv reflect.Value, path string, errs *Errors) {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if !rv.IsNil() {
+			validate(rv.Elem(), path, errs)
+		}
+	case reflect.Map:
+		if rv.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			validate(iter.Value(), join(path, fmt.Sprint(iter.Key().Interface())), errs)
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field, fv := rt.Field(i), rv.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldPath := join(path, fieldName(field))
+			if rules := field.Tag.Get(ValidateTag); rules != "" {
+				for _, rule := range strings.Split(rules, ",") {
+					if err := check(fv, rule); err != "" {
+						*errs = append(*errs, fieldPath+" "+err)
+					}
+				}
+			}
+			validate(fv, fieldPath, errs)
+		}
+	}
+}
+
+func check(fv reflect.Value, rule string) string {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	// optional fields are pointers, nil is empty
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	if name == "required" {
+		if isEmpty(fv) {
+			return "is required"
+		}
+		return ""
+	}
+	if isEmpty(fv) {
+		return ""
+	}
+	switch name {
+	case "min", "max":
+		limit, _ := strconv.ParseFloat(arg, 64)
+		n := size(fv)
+		if name == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s, got %v", arg, n)
+		}
+		if name == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s, got %v", arg, n)
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		for _, option := range options {
+			if strings.EqualFold(fv.String(), option) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s], got %q", strings.Join(options, " "), fv.String())
+	case "hostport":
+		if _, _, err := net.SplitHostPort(fv.String()); err != nil {
+			return fmt.Sprintf("must be host:port, got %q", fv.String())
+		}
+	case "regexp":
+		for _, s := range strs(fv) {
+			if _, err := regexp.Compile(s); err != nil {
+				return fmt.Sprintf("has an invalid regexp %q: %s", s, err)
+			}
+		}
+	}
+	return ""
+}
+
+func isEmpty(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+func size(fv reflect.Value) float64 {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fv.Float()
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(fv.Len())
+	}
+	return 0
+}
+
+func strs(fv reflect.Value) []string {
+	switch fv.Kind() {
+	case reflect.String:
+		return []string{fv.String()}
+	case reflect.Slice:
+		if s, ok := fv.Interface().([]string); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+func setString(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.String {
+			fv.Set(reflect.ValueOf(strings.Split(s, ",")))
+		}
+	}
+	return nil
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get(nameTag), ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// IsLeaf reports whether the dotted, case insensitive path names a leaf field of the struct pointed by ptr,
+// the keys of maps of structs are path elements.
+func IsLeaf(ptr interface{}, path string) bool {
+	t := reflect.TypeOf(ptr)
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct:
+			t = t.Elem()
+		case t.Kind() == reflect.Struct:
+			field, ok := fieldByName(t, name)
+			if !ok {
+				return false
+			}
+			t = field.Type
+		default:
+			return false
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Struct && (t.Kind() != reflect.Map || t.Elem().Kind() != reflect.Struct)
+}
+
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" && strings.EqualFold(fieldName(f), name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Walk calls fn with the path, field and value of every leaf field of the struct pointed by ptr,
+// nil pointers are skipped and maps of structs are walked by key.
+func Walk(ptr interface{}, fn func(path string, field reflect.StructField, fv reflect.Value)) {
+	walk(reflect.ValueOf(ptr), "", reflect.StructField{}, fn)
+}
+
+func walk(rv reflect.Value, path string, field reflect.StructField, fn func(string, reflect.StructField, reflect.Value)) {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if !rv.IsNil() {
+			walk(rv.Elem(), path, field, fn)
+		}
+	case reflect.Map:
+		if rv.Type().Elem().Kind() != reflect.Struct {
+			fn(path, field, rv)
+			return
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		for _, key := range keys {
+			walk(rv.MapIndex(key), join(path, fmt.Sprint(key.Interface())), field, fn)
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			if f := rt.Field(i); f.PkgPath == "" {
+				walk(rv.Field(i), join(path, fieldName(f)), f, fn)
+			}
+		}
+	default:
+		fn(path, field, rv)
+	}
+}
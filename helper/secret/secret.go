@@ -0,0 +1,130 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	// FilePrefix reads the value from a file, e.g. secret:///run/secrets/sign_secret.
+	FilePrefix = "secret://"
+	// EnvPrefix reads the value from an environment variable, e.g. env://SIGN_SECRET.
+	EnvPrefix = "env://"
+	// EncPrefix decrypts the value with the AES-GCM key file, e.g. enc://<base64 nonce and ciphertext>.
+	EncPrefix = "enc://"
+	// KeySize is the size of the keys made by NewKey, AES-256.
+	KeySize = 32
+)
+
+var ErrKeyFile = errors.New("secret key file is not set")
+
+// IsRef reports whether s refers to a secret instead of holding a plain value.
+func IsRef(s string) bool {
+	return strings.HasPrefix(s, FilePrefix) || strings.HasPrefix(s, EnvPrefix) || strings.HasPrefix(s, EncPrefix)
+}
+
+// Resolve returns the value s refers to, plain values are returned as is.
+// keyFile is only read for enc:// values.
+func Resolve(s, keyFile string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, FilePrefix):
+		b, err := ioutil.ReadFile(strings.TrimPrefix(s, FilePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(s, EnvPrefix):
+		name := strings.TrimPrefix(s, EnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("env %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(s, EncPrefix):
+		key, err := ReadKey(keyFile)
+		if err != nil {
+			return "", err
+		}
+		return Decrypt(key, s)
+	}
+	return s, nil
+}
+
+// NewKey returns a random key, base64 encoded as written in key files.
+func NewKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ReadKey reads a base64 encoded AES key of 16, 24 or 32 bytes.
+func ReadKey(file string) ([]byte, error) {
+	if file == "" {
+		return nil, ErrKeyFile
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("secret key file %s is not base64: %s", file, err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("secret key file %s holds %d bytes, want 16, 24 or 32", file, len(key))
+}
+
+// Encrypt seals plaintext with key and returns it as an enc:// value.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens an enc:// value sealed by Encrypt.
+func Decrypt(key []byte, s string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, EncPrefix))
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not base64: %s", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("encrypted value can not be decrypted with the key")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
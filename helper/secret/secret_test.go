@@ -0,0 +1,101 @@
+package secret
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	s, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := newKey(t)
+	enc, err := Encrypt(key, "p@ss word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enc, EncPrefix) || !IsRef(enc) {
+		t.Fatalf("Encrypt = %q, want an %s value", enc, EncPrefix)
+	}
+	if again, _ := Encrypt(key, "p@ss word"); again == enc {
+		t.Fatal("Encrypt reused its nonce")
+	}
+	if got, err := Decrypt(key, enc); err != nil || got != "p@ss word" {
+		t.Fatalf("Decrypt = %q, %v, want p@ss word", got, err)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	key := newKey(t)
+	enc, err := Encrypt(key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(enc, EncPrefix))
+	sealed[len(sealed)-1] ^= 1
+	tampered := EncPrefix + base64.StdEncoding.EncodeToString(sealed)
+	for name, c := range map[string]struct {
+		key []byte
+		s   string
+	}{
+		"wrong key":  {newKey(t), enc},
+		"tampered":   {key, tampered},
+		"short":      {key, EncPrefix + base64.StdEncoding.EncodeToString([]byte("short"))},
+		"not base64": {key, EncPrefix + "%%%"},
+		"bad key":    {[]byte("short"), enc},
+	} {
+		if got, err := Decrypt(c.key, c.s); err == nil {
+			t.Errorf("%s: Decrypt = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sign_secret")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := NewKey()
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := ReadKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := Encrypt(k, "from-enc")
+	t.Setenv("SECRET_TEST_VALUE", "from-env")
+
+	for s, want := range map[string]string{
+		"plain":                         "plain",
+		FilePrefix + file:               "from-file",
+		EnvPrefix + "SECRET_TEST_VALUE": "from-env",
+		enc:                             "from-enc",
+	} {
+		if got, err := Resolve(s, keyFile); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	for _, s := range []string{FilePrefix + filepath.Join(dir, "missing"), EnvPrefix + "SECRET_TEST_MISSING"} {
+		if _, err := Resolve(s, keyFile); err == nil {
+			t.Errorf("Resolve(%q) has no error", s)
+		}
+	}
+	if _, err := Resolve(enc, ""); err != ErrKeyFile {
+		t.Errorf("Resolve without key file error = %v, want %v", err, ErrKeyFile)
+	}
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/consts"
+	"github.com/ZYallers/rpcx-framework/helper/redact"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/protocol"
+	"github.com/smallnest/rpcx/server"
+	"github.com/smallnest/rpcx/share"
+	"go.uber.org/zap"
+)
+
+// AccessPlugin writes one access log entry for every rpc call.
+type AccessPlugin struct {
+	config *types.AccessConfig
+	logger *zap.Logger
+	redact map[string]bool
+	failed func(*types.Access, error)
+}
+
+func NewAccessPlugin(dir string, c *types.AccessConfig) *AccessPlugin {
+	file := c.File
+	if file == "" {
+		file = "access"
+	}
+	p := &AccessPlugin{config: c, logger: types.NewZapLogger(dir, file, &c.Log), redact: map[string]bool{}}
+	for _, key := range c.Redact {
+		p.redact[strings.ToLower(key)] = true
+	}
+	p.failed = func(a *types.Access, err error) { p.write(a, nil, err) }
+	return p
+}
+
+func (p *AccessPlugin) PostReadRequest(ctx context.Context, r *protocol.Message, e error) error {
+	sc, ok := ctx.(*share.Context)
+	if !ok || e != nil || r == nil || r.IsHeartbeat() {
+		return nil
+	}
+	transport, caller := callerOf(ctx)
+	a := &types.Access{
+		Start:     time.Now(),
+		Caller:    caller,
+		Transport: transport,
+		Service:   r.ServicePath,
+		Method:    r.ServiceMethod,
+		ReqSize:   len(r.Payload),
+	}
+	// the gateway skips PostWriteResponse when the handler fails, the restful handlers log those calls themselves,
+	// calls failing before their handler runs, like unknown methods or bad payloads, are still not logged.
+	if transport == consts.HTTPTransport {
+		a.OnFailed(p.failed)
+	}
+	sc.SetValue(types.AccessContextKey, a)
+	return nil
+}
+
+func (p *AccessPlugin) PostWriteResponse(ctx context.Context, req *protocol.Message, resp *protocol.Message, err error) error {
+	if a := types.AccessFromContext(ctx); a != nil {
+		p.write(a, resp, err)
+	}
+	return nil
+}
+
+func (p *AccessPlugin) write(a *types.Access, resp *protocol.Message, err error) {
+	// the failed calls, handler errors included, are always logged, 0 is the code of the calls without reply envelope
+	failed := err != nil || a.Code != 0 && a.Code != http.StatusOK
+	if !failed && p.config.Sample > 0 && p.config.Sample < 1 && rand.Float64() >= p.config.Sample {
+		return
+	}
+	if resp != nil && a.Transport != consts.JSONRPCTransport {
+		a.RespSize = len(resp.Payload)
+	}
+	fields := []zap.Field{
+		zap.String("caller", a.Caller),
+		zap.String("transport", a.Transport),
+		zap.String("service", a.Service),
+		zap.String("method", a.Method),
+		zap.String("version", a.Version),
+		zap.String("sign", a.Signed),
+		zap.String("login", a.Logged),
+		zap.Int("code", a.Code),
+		zap.Duration("latency", time.Since(a.Start)),
+		zap.Int("req_size", a.ReqSize),
+		zap.Int("resp_size", a.RespSize),
+	}
+	if p.config.Args && a.Args != nil {
+		fields = append(fields, zap.Any("args", p.redactArgs(a.Args)))
+	}
+	if err != nil {
+		p.logger.Error(redact.String(err.Error()), fields...)
+	} else {
+		p.logger.Info("access", fields...)
+	}
+}
+
+// redactArgs applies the central redact policy and then the access log's own keys.
+func (p *AccessPlugin) redactArgs(args map[string]interface{}) map[string]interface{} {
+	cp := redact.Map(args)
+	for k := range cp {
+		if p.redact[strings.ToLower(k)] {
+			cp[k] = redact.DefaultMask
+		}
+	}
+	return cp
+}
+
+// callerOf returns the transport and remote address of the request,
+// the http gateway stores the remote address as a string while tcp and jsonrpc store the net.Conn.
+func callerOf(ctx context.Context) (string, string) {
+	switch conn := ctx.Value(server.RemoteConnContextKey).(type) {
+	case string:
+		return consts.HTTPTransport, conn
+	case net.Conn:
+		if ctx.Value(server.HttpConnContextKey) != nil {
+			return consts.JSONRPCTransport, conn.RemoteAddr().String()
+		}
+		return consts.TCPTransport, conn.RemoteAddr().String()
+	}
+	return consts.TCPTransport, ""
+}
@@ -0,0 +1,266 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/log"
+	"github.com/smallnest/rpcx/protocol"
+	"github.com/smallnest/rpcx/share"
+)
+
+const (
+	defaultDebugMaxLength    = 256
+	defaultDebugRequestIdKey = "request_id"
+)
+
+// BasePlugin is kept for compatibility, a zero value logs nothing until it is enabled by Update.
+//
+// Deprecated: use NewDebugPlugin.
+type BasePlugin = DebugPlugin
+
+// DebugPlugin logs the server plugin hooks selected by its types.DebugConfig,
+// the config can be replaced at runtime by Update.
+type DebugPlugin struct {
+	config atomic.Value
+}
+
+type debugTarget struct {
+	caller, service, method, requestId string
+}
+
+func NewDebugPlugin(c *types.DebugConfig) *DebugPlugin {
+	p := &DebugPlugin{}
+	p.Update(c)
+	return p
+}
+
+func (p *DebugPlugin) Config() *types.DebugConfig {
+	c, _ := p.config.Load().(*types.DebugConfig)
+	return c
+}
+
+func (p *DebugPlugin) Update(c *types.DebugConfig) {
+	if c == nil {
+		c = &types.DebugConfig{}
+	}
+	p.config.Store(c)
+}
+
+func (p *DebugPlugin) Register(name string, rcvr interface{}, metadata string) error {
+	if c, ok := p.on("Register"); ok && p.match(c, debugTarget{service: name}) {
+		p.print(c, "Register", "name:%s, rcvr:%T, metadata:%s", name, rcvr, metadata)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) Unregister(name string) error {
+	if c, ok := p.on("Unregister"); ok && p.match(c, debugTarget{service: name}) {
+		p.print(c, "Unregister", "name:%s", name)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) RegisterFunction(serviceName, fname string, fn interface{}, metadata string) error {
+	if c, ok := p.on("RegisterFunction"); ok && p.match(c, debugTarget{service: serviceName, method: fname}) {
+		p.print(c, "RegisterFunction", "serviceName:%s, fname:%s, metadata:%s", serviceName, fname, metadata)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) HandleConnAccept(conn net.Conn) (net.Conn, bool) {
+	if c, ok := p.on("HandleConnAccept"); ok && p.match(c, debugTarget{caller: conn.RemoteAddr().String()}) {
+		p.print(c, "HandleConnAccept", "LocalAddr:%s, RemoteAddr:%s", conn.LocalAddr(), conn.RemoteAddr())
+	}
+	return conn, true
+}
+
+func (p *DebugPlugin) HandleConnClose(conn net.Conn) bool {
+	if c, ok := p.on("HandleConnClose"); ok && p.match(c, debugTarget{caller: conn.RemoteAddr().String()}) {
+		p.print(c, "HandleConnClose", "LocalAddr:%s, RemoteAddr:%s", conn.LocalAddr(), conn.RemoteAddr())
+	}
+	return true
+}
+
+func (p *DebugPlugin) PreReadRequest(ctx context.Context) error {
+	if c, ok := p.on("PreReadRequest"); ok && p.match(c, p.target(ctx, c, nil)) {
+		p.print(c, "PreReadRequest", "")
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PostReadRequest(ctx context.Context, r *protocol.Message, e error) error {
+	if c, ok := p.on("PostReadRequest"); ok && p.match(c, p.target(ctx, c, r)) {
+		p.print(c, "PostReadRequest", "%s, err:%v", p.message(c, r), e)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PreHandleRequest(ctx context.Context, r *protocol.Message) error {
+	if c, ok := p.on("PreHandleRequest"); ok && p.match(c, p.target(ctx, c, r)) {
+		p.print(c, "PreHandleRequest", "%s", p.message(c, r))
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PreCall(ctx context.Context, serviceName, methodName string, args interface{}) (interface{}, error) {
+	if c, ok := p.on("PreCall"); ok && p.match(c, p.callTarget(ctx, c, serviceName, methodName)) {
+		p.print(c, "PreCall", "serviceName:%s, methodName:%s, args:%s", serviceName, methodName, p.truncate(c, fmt.Sprintf("%+v", args)))
+	}
+	return args, nil
+}
+
+func (p *DebugPlugin) PostCall(ctx context.Context, serviceName, methodName string, args, reply interface{}) (interface{}, error) {
+	if c, ok := p.on("PostCall"); ok && p.match(c, p.callTarget(ctx, c, serviceName, methodName)) {
+		p.print(c, "PostCall", "serviceName:%s, methodName:%s, args:%s, reply:%s", serviceName, methodName,
+			p.truncate(c, fmt.Sprintf("%+v", args)), p.truncate(c, fmt.Sprintf("%+v", reply)))
+	}
+	return reply, nil
+}
+
+func (p *DebugPlugin) PreWriteResponse(ctx context.Context, req *protocol.Message, resp *protocol.Message, err error) error {
+	if c, ok := p.on("PreWriteResponse"); ok && p.match(c, p.target(ctx, c, req)) {
+		p.print(c, "PreWriteResponse", "req:{%s}, resp:{%s}, err:%v", p.message(c, req), p.message(c, resp), err)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PostWriteResponse(ctx context.Context, req *protocol.Message, resp *protocol.Message, err error) error {
+	if c, ok := p.on("PostWriteResponse"); ok && p.match(c, p.target(ctx, c, req)) {
+		p.print(c, "PostWriteResponse", "req:{%s}, resp:{%s}, err:%v", p.message(c, req), p.message(c, resp), err)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PreWriteRequest(ctx context.Context) error {
+	if c, ok := p.on("PreWriteRequest"); ok && p.match(c, p.target(ctx, c, nil)) {
+		p.print(c, "PreWriteRequest", "")
+	}
+	return nil
+}
+
+func (p *DebugPlugin) PostWriteRequest(ctx context.Context, r *protocol.Message, e error) error {
+	if c, ok := p.on("PostWriteRequest"); ok && p.match(c, p.target(ctx, c, r)) {
+		p.print(c, "PostWriteRequest", "%s, err:%v", p.message(c, r), e)
+	}
+	return nil
+}
+
+func (p *DebugPlugin) HeartbeatRequest(ctx context.Context, req *protocol.Message) error {
+	if c, ok := p.on("HeartbeatRequest"); ok && p.match(c, p.target(ctx, c, req)) {
+		p.print(c, "HeartbeatRequest", "%s", p.message(c, req))
+	}
+	return nil
+}
+
+func (p *DebugPlugin) target(ctx context.Context, c *types.DebugConfig, r *protocol.Message) debugTarget {
+	t := debugTarget{}
+	if ctx != nil {
+		_, t.caller = callerOf(ctx)
+	}
+	key := c.RequestIdKey
+	if key == "" {
+		key = defaultDebugRequestIdKey
+	}
+	if r != nil {
+		t.service, t.method, t.requestId = r.ServicePath, r.ServiceMethod, r.Metadata[key]
+	} else if ctx != nil {
+		if meta, ok := ctx.Value(share.ReqMetaDataKey).(map[string]string); ok {
+			t.requestId = meta[key]
+		}
+	}
+	return t
+}
+
+func (p *DebugPlugin) callTarget(ctx context.Context, c *types.DebugConfig, serviceName, methodName string) debugTarget {
+	t := p.target(ctx, c, nil)
+	t.service, t.method = serviceName, methodName
+	return t
+}
+
+// on reports whether debug is enabled and the hook selected, checked before the target of the hook is looked up.
+func (p *DebugPlugin) on(hook string) (*types.DebugConfig, bool) {
+	c := p.Config()
+	if c == nil || !c.Enable {
+		return nil, false
+	}
+	if len(c.Hooks) > 0 && !containsFold(c.Hooks, hook) {
+		return nil, false
+	}
+	return c, true
+}
+
+// match reports whether the target passes every configured filter.
+func (p *DebugPlugin) match(c *types.DebugConfig, t debugTarget) bool {
+	if len(c.Services) > 0 && !containsFold(c.Services, t.service) {
+		return false
+	}
+	if len(c.Methods) > 0 && !containsFold(c.Methods, t.method) {
+		return false
+	}
+	if len(c.RequestIds) > 0 && !containsFold(c.RequestIds, t.requestId) {
+		return false
+	}
+	if len(c.Callers) > 0 {
+		host, _, _ := net.SplitHostPort(t.caller)
+		if !containsFold(c.Callers, t.caller) && !containsFold(c.Callers, host) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *DebugPlugin) print(c *types.DebugConfig, hook, format string, v ...interface{}) {
+	msg := "DebugPlugin." + hook + "-> " + fmt.Sprintf(format, v...)
+	if strings.ToLower(c.Level) == "info" {
+		log.Info(msg)
+	} else {
+		log.Debug(msg)
+	}
+}
+
+func (p *DebugPlugin) message(c *types.DebugConfig, r *protocol.Message) string {
+	if r == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("service:%s, method:%s, seq:%d, metadata:%v, payload(%d):%s",
+		r.ServicePath, r.ServiceMethod, r.Seq(), r.Metadata, len(r.Payload), p.payload(c, r))
+}
+
+// payload decodes the message payload with its codec so msgpack bodies are readable.
+func (p *DebugPlugin) payload(c *types.DebugConfig, r *protocol.Message) string {
+	if len(r.Payload) == 0 {
+		return ""
+	}
+	if cc := share.Codecs[r.SerializeType()]; cc != nil && r.CompressType() == protocol.None {
+		var v interface{}
+		if err := cc.Decode(r.Payload, &v); err == nil {
+			return p.truncate(c, fmt.Sprintf("%v", v))
+		}
+	}
+	return p.truncate(c, fmt.Sprintf("%q", r.Payload))
+}
+
+func (p *DebugPlugin) truncate(c *types.DebugConfig, s string) string {
+	max := c.MaxLength
+	if max <= 0 {
+		max = defaultDebugMaxLength
+	}
+	if len(s) > max {
+		return s[:max] + "...(truncated)"
+	}
+	return s
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
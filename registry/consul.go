@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+	"github.com/smallnest/rpcx/serverplugin"
+)
+
+func init() {
+	Register(Consul, func(d *types.Discovery) (Registry, error) { return &consulRegistry{d: d}, nil })
+}
+
+// consulRegistry keeps the servers in the consul kv store, under "<basePath>/<service>/<addr>".
+type consulRegistry struct{ d *types.Discovery }
+
+func (r *consulRegistry) Plugin(basePath, addr string) (server.Plugin, error) {
+	p := &serverplugin.ConsulRegisterPlugin{
+		ServiceAddress: addr,
+		ConsulServers:  r.d.Addr,
+		BasePath:       basePath,
+		UpdateInterval: r.d.UpdateInterval,
+		Options:        storeConfig(r.d),
+	}
+	return p, p.Start()
+}
+
+func (r *consulRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	// consul discovery always allows a missing service
+	return client.NewConsulDiscovery(basePath, service, r.d.Addr, storeConfig(r.d))
+}
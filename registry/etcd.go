@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"github.com/ZYallers/rpcx-framework/types"
+	etcdclient "github.com/rpcxio/rpcx-etcd/client"
+	"github.com/rpcxio/rpcx-etcd/serverplugin"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+)
+
+func init() {
+	Register(Etcd, func(d *types.Discovery) (Registry, error) { return &etcdRegistry{d: d}, nil })
+}
+
+// etcdRegistry keeps the servers in etcd v3, under "<basePath>/<service>/<addr>".
+type etcdRegistry struct{ d *types.Discovery }
+
+func (r *etcdRegistry) Plugin(basePath, addr string) (server.Plugin, error) {
+	p := &serverplugin.EtcdV3RegisterPlugin{
+		ServiceAddress: addr,
+		EtcdServers:    r.d.Addr,
+		BasePath:       basePath,
+		UpdateInterval: r.d.UpdateInterval,
+		Options:        storeConfig(r.d),
+	}
+	return p, p.Start()
+}
+
+func (r *etcdRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	return etcdclient.NewEtcdV3Discovery(basePath, service, r.d.Addr, allowMissing, storeConfig(r.d))
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/smallnest/rpcx/client"
+)
+
+// FallbackDiscovery discovers the servers of a service in an isolated namespace,
+// and in the shared namespace as long as the service does not run in the isolated one.
+type FallbackDiscovery struct {
+	local, shared client.ServiceDiscovery
+	localCh       chan []*client.KVPair
+	sharedCh      chan []*client.KVPair
+	mu            sync.Mutex
+	chans         []chan []*client.KVPair
+	stopCh        chan struct{}
+	once          sync.Once
+}
+
+func NewFallbackDiscovery(local, shared client.ServiceDiscovery) *FallbackDiscovery {
+	d := &FallbackDiscovery{
+		local:    local,
+		shared:   shared,
+		localCh:  local.WatchService(),
+		sharedCh: shared.WatchService(),
+		stopCh:   make(chan struct{}),
+	}
+	go d.watch()
+	return d
+}
+
+func (d *FallbackDiscovery) GetServices() []*client.KVPair {
+	if pairs := d.local.GetServices(); len(pairs) > 0 {
+		return pairs
+	}
+	return d.shared.GetServices()
+}
+
+func (d *FallbackDiscovery) WatchService() chan []*client.KVPair {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch := make(chan []*client.KVPair, 10)
+	d.chans = append(d.chans, ch)
+	return ch
+}
+
+func (d *FallbackDiscovery) RemoveWatcher(ch chan []*client.KVPair) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	chans := d.chans[:0]
+	for _, c := range d.chans {
+		if c != ch {
+			chans = append(chans, c)
+		}
+	}
+	d.chans = chans
+}
+
+func (d *FallbackDiscovery) Clone(servicePath string) (client.ServiceDiscovery, error) {
+	local, err := d.local.Clone(servicePath)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := d.shared.Clone(servicePath)
+	if err != nil {
+		local.Close()
+		return nil, err
+	}
+	return NewFallbackDiscovery(local, shared), nil
+}
+
+func (d *FallbackDiscovery) SetFilter(filter client.ServiceDiscoveryFilter) {
+	d.local.SetFilter(filter)
+	d.shared.SetFilter(filter)
+}
+
+func (d *FallbackDiscovery) Close() {
+	d.once.Do(func() {
+		close(d.stopCh)
+		d.local.RemoveWatcher(d.localCh)
+		d.shared.RemoveWatcher(d.sharedCh)
+		d.local.Close()
+		d.shared.Close()
+	})
+}
+
+// watch notifies the watchers with the servers in use whenever either namespace changes.
+func (d *FallbackDiscovery) watch() {
+	defer safe.Defer()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-d.localCh:
+		case <-d.sharedCh:
+		}
+		pairs := d.GetServices()
+		d.mu.Lock()
+		for _, ch := range d.chans {
+			select {
+			case ch <- pairs:
+			default:
+			}
+		}
+		d.mu.Unlock()
+	}
+}
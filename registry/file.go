@@ -0,0 +1,257 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/fsnotify/fsnotify"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	Register(File, func(d *types.Discovery) (Registry, error) {
+		if d.File == "" {
+			return nil, fmt.Errorf("file registry needs a file")
+		}
+		return &fileRegistry{d: d}, nil
+	})
+}
+
+// fileMu serializes the read-modify-write of the peers files by the servers of the process,
+// lockPeersFile the one by the other processes.
+var fileMu sync.Mutex
+
+// fileRegistry discovers the servers listed by service in a JSON or YAML file, which is watched for changes:
+//
+//	{"user": ["tcp@127.0.0.1:9001"], "order": ["127.0.0.1:9002", "127.0.0.1:9003?weight=2"]}
+//
+// With d.Register the servers add their address to the file and remove it when they stop, a missing file is
+// then created. Servers killed without stopping leave their address behind until the file is edited.
+type fileRegistry struct{ d *types.Discovery }
+
+func (r *fileRegistry) Plugin(basePath, addr string) (server.Plugin, error) {
+	if !r.d.Register {
+		return nil, nil
+	}
+	return &filePlugin{file: r.d.File, addr: addr, services: map[string]bool{}}, nil
+}
+
+func (r *fileRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	peers, err := readPeersFile(r.d.File)
+	if err != nil {
+		return nil, err
+	}
+	pairs := peerPairs(peers[service])
+	if len(pairs) == 0 && !allowMissing {
+		return nil, fmt.Errorf("service %s has no peer in %s", service, r.d.File)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// watch the directory, editors replace the file rather than writing it
+	if err := watcher.Add(filepath.Dir(r.d.File)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	d := &fileDiscovery{r: r, service: service, watcher: watcher, MultipleServersDiscovery: &client.MultipleServersDiscovery{}}
+	d.Update(pairs)
+	go d.watch(pairs)
+	return d, nil
+}
+
+// readPeersFile reads the peers of every service, a missing file has none.
+func readPeersFile(file string) (map[string][]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	peers := map[string][]string{}
+	// yaml is a superset of json
+	if err := yaml.Unmarshal(b, &peers); err != nil {
+		return nil, fmt.Errorf("peers file %s error: %s", file, err)
+	}
+	return peers, nil
+}
+
+// writePeersFile replaces the file atomically, as YAML for .yaml and .yml files and as JSON otherwise.
+func writePeersFile(file string, peers map[string][]string) error {
+	var b []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		b, err = yaml.Marshal(peers)
+	default:
+		b, err = json.MarshalIndent(peers, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// updatePeersFile applies fn to the peers of service in file and writes the file when they change.
+func updatePeersFile(file, service string, fn func(peers []string) []string) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	unlock, err := lockPeersFile(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	all, err := readPeersFile(file)
+	if err != nil {
+		return err
+	}
+	peers := fn(all[service])
+	if reflect.DeepEqual(peers, all[service]) {
+		return nil
+	}
+	if len(peers) == 0 {
+		delete(all, service)
+	} else {
+		all[service] = peers
+	}
+	return writePeersFile(file, all)
+}
+
+// withoutPeer returns peers without the ones at addr, whatever their metadata.
+func withoutPeer(peers []string, addr string) []string {
+	var rest []string
+	for _, peer := range peers {
+		if pairs := peerPairs([]string{peer}); len(pairs) == 1 && pairs[0].Key == addr {
+			continue
+		}
+		rest = append(rest, peer)
+	}
+	return rest
+}
+
+// filePlugin adds the address of the server to the peers of its services in the file.
+type filePlugin struct {
+	file string
+	addr string
+
+	mu       sync.Mutex
+	services map[string]bool
+}
+
+func (p *filePlugin) Register(name string, rcvr interface{}, metadata string) error {
+	if _, err := url.ParseQuery(metadata); err != nil {
+		return err
+	}
+	peer := p.addr
+	if metadata != "" {
+		peer += "?" + metadata
+	}
+	err := updatePeersFile(p.file, name, func(peers []string) []string {
+		return append(withoutPeer(peers, p.addr), peer)
+	})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.services[name] = true
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *filePlugin) RegisterFunction(serviceName, fname string, fn interface{}, metadata string) error {
+	p.mu.Lock()
+	ok := p.services[serviceName]
+	p.mu.Unlock()
+	if ok {
+		return nil
+	}
+	return p.Register(serviceName, fn, metadata)
+}
+
+func (p *filePlugin) Unregister(name string) error {
+	p.mu.Lock()
+	delete(p.services, name)
+	p.mu.Unlock()
+	return updatePeersFile(p.file, name, func(peers []string) []string { return withoutPeer(peers, p.addr) })
+}
+
+// Stop removes the address of the server from the file.
+func (p *filePlugin) Stop() error {
+	p.mu.Lock()
+	names := make([]string, 0, len(p.services))
+	for name := range p.services {
+		names = append(names, name)
+	}
+	p.mu.Unlock()
+	var err error
+	for _, name := range names {
+		if e := p.Unregister(name); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+type fileDiscovery struct {
+	*client.MultipleServersDiscovery
+	r       *fileRegistry
+	service string
+	watcher *fsnotify.Watcher
+	once    sync.Once
+}
+
+func (d *fileDiscovery) Clone(servicePath string) (client.ServiceDiscovery, error) {
+	return d.r.Discovery("", servicePath, true)
+}
+
+func (d *fileDiscovery) Close() {
+	d.once.Do(func() { _ = d.watcher.Close() })
+}
+
+func (d *fileDiscovery) watch(pairs []*client.KVPair) {
+	defer safe.Defer()
+	file := filepath.Clean(d.r.d.File)
+	var f failure
+	for {
+		select {
+		case event, ok := <-d.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != file || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			peers, err := readPeersFile(file)
+			if err != nil {
+				f.fail("file registry reload error: %v", err)
+				continue
+			}
+			f.ok("file registry %s is reloaded", file)
+			if next := peerPairs(peers[d.service]); !reflect.DeepEqual(next, pairs) {
+				pairs = next
+				d.Update(pairs)
+			}
+		case err, ok := <-d.watcher.Errors:
+			if !ok {
+				return
+			}
+			f.fail("file registry watcher error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package registry
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockPeersFile takes the advisory lock of the sidecar lock file of file, shared by the processes updating it.
+// The peers file itself is replaced on every write, so it cannot hold the lock.
+func lockPeersFile(file string) (func(), error) {
+	f, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/smallnest/rpcx/client"
+)
+
+// FilterDiscovery only lets through the servers of a discovery passing its filter, which can be changed at
+// any time, unlike the filters of the rpcx discoveries only applied to the later updates if at all.
+type FilterDiscovery struct {
+	sd     client.ServiceDiscovery
+	sdCh   chan []*client.KVPair
+	mu     sync.Mutex
+	filter client.ServiceDiscoveryFilter
+	chans  []chan []*client.KVPair
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func NewFilterDiscovery(sd client.ServiceDiscovery, filter client.ServiceDiscoveryFilter) *FilterDiscovery {
+	d := &FilterDiscovery{sd: sd, sdCh: sd.WatchService(), filter: filter, stopCh: make(chan struct{})}
+	go d.watch()
+	return d
+}
+
+func (d *FilterDiscovery) GetServices() []*client.KVPair {
+	pairs := d.sd.GetServices()
+	d.mu.Lock()
+	filter := d.filter
+	d.mu.Unlock()
+	if filter == nil {
+		return pairs
+	}
+	filtered := make([]*client.KVPair, 0, len(pairs))
+	for _, pair := range pairs {
+		if filter(pair) {
+			filtered = append(filtered, pair)
+		}
+	}
+	return filtered
+}
+
+func (d *FilterDiscovery) WatchService() chan []*client.KVPair {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch := make(chan []*client.KVPair, 10)
+	d.chans = append(d.chans, ch)
+	return ch
+}
+
+func (d *FilterDiscovery) RemoveWatcher(ch chan []*client.KVPair) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	chans := d.chans[:0]
+	for _, c := range d.chans {
+		if c != ch {
+			chans = append(chans, c)
+		}
+	}
+	d.chans = chans
+}
+
+func (d *FilterDiscovery) Clone(servicePath string) (client.ServiceDiscovery, error) {
+	sd, err := d.sd.Clone(servicePath)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return NewFilterDiscovery(sd, d.filter), nil
+}
+
+// SetFilter replaces the filter and notifies the watchers, nil lets every server through.
+func (d *FilterDiscovery) SetFilter(filter client.ServiceDiscoveryFilter) {
+	d.mu.Lock()
+	d.filter = filter
+	d.mu.Unlock()
+	d.notify()
+}
+
+func (d *FilterDiscovery) Close() {
+	d.once.Do(func() {
+		close(d.stopCh)
+		d.sd.RemoveWatcher(d.sdCh)
+		d.sd.Close()
+	})
+}
+
+func (d *FilterDiscovery) notify() {
+	pairs := d.GetServices()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ch := range d.chans {
+		select {
+		case ch <- pairs:
+		default:
+		}
+	}
+}
+
+func (d *FilterDiscovery) watch() {
+	defer safe.Defer()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-d.sdCh:
+		}
+		d.notify()
+	}
+}
@@ -0,0 +1,346 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZYallers/rpcx-framework/helper/safe"
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+)
+
+const (
+	nacosTimeout        = 3 * time.Second
+	nacosBeatInterval   = 5 * time.Second
+	nacosNetworkMetaKey = "rpcx_network"
+)
+
+var nacosGroupRegex = regexp.MustCompile(`[^a-zA-Z0-9_.:-]+`)
+
+func init() {
+	Register(Nacos, func(d *types.Discovery) (Registry, error) {
+		if len(d.Addr) == 0 {
+			return nil, errors.New("nacos registry needs an address")
+		}
+		return &nacosRegistry{d: d, http: &http.Client{Timeout: nacosTimeout}}, nil
+	})
+}
+
+// nacosRegistry registers ephemeral instances through the nacos open api, the services are nacos services
+// of the group named after the base path, e.g. "app_rpcx_production".
+type nacosRegistry struct {
+	d    *types.Discovery
+	http *http.Client
+
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+type nacosInstance struct {
+	Ip       string            `json:"ip"`
+	Port     int               `json:"port"`
+	Healthy  bool              `json:"healthy"`
+	Enabled  bool              `json:"enabled"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+func nacosGroup(basePath string) string {
+	if group := nacosGroupRegex.ReplaceAllString(strings.Trim(basePath, "/"), "_"); group != "" {
+		return group
+	}
+	return "DEFAULT_GROUP"
+}
+
+func (r *nacosRegistry) Plugin(basePath, addr string) (server.Plugin, error) {
+	network, hostport := "tcp", addr
+	if i := strings.Index(addr, "@"); i >= 0 {
+		network, hostport = addr[:i], addr[i+1:]
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, err
+	}
+	p := &nacosPlugin{r: r, group: nacosGroup(basePath), network: network, ip: host, port: port,
+		services: map[string]url.Values{}, stopCh: make(chan struct{})}
+	go p.beat()
+	return p, nil
+}
+
+func (r *nacosRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	d := &nacosDiscovery{r: r, group: nacosGroup(basePath), service: service,
+		MultipleServersDiscovery: &client.MultipleServersDiscovery{}, stopCh: make(chan struct{})}
+	pairs, err := d.fetch()
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 && !allowMissing {
+		return nil, fmt.Errorf("service %s has no nacos instance in group %s", service, d.group)
+	}
+	d.Update(pairs)
+	go d.poll(pairs)
+	return d, nil
+}
+
+// call sends an open api request to the first nacos server answering.
+func (r *nacosRegistry) call(method, path string, params url.Values) ([]byte, error) {
+	var lastErr error
+	for _, addr := range r.d.Addr {
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		if r.d.Username != "" {
+			token, err := r.accessToken(addr)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			params.Set("accessToken", token)
+		}
+		req, err := http.NewRequest(method, addr+path+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := r.http.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("nacos %s %s: %s %s", method, path, resp.Status, b)
+		}
+		return b, nil
+	}
+	return nil, lastErr
+}
+
+func (r *nacosRegistry) accessToken(addr string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" && time.Now().Before(r.tokenExp) {
+		return r.token, nil
+	}
+	resp, err := r.http.PostForm(addr+"/nacos/v1/auth/login", url.Values{"username": {r.d.Username}, "password": {r.d.Password}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var login struct {
+		AccessToken string `json:"accessToken"`
+		TokenTtl    int64  `json:"tokenTtl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil || login.AccessToken == "" {
+		return "", fmt.Errorf("nacos login failed: %s", resp.Status)
+	}
+	r.token = login.AccessToken
+	r.tokenExp = time.Now().Add(time.Duration(login.TokenTtl)*time.Second - time.Minute)
+	return r.token, nil
+}
+
+// nacosPlugin registers the services of the server as ephemeral instances and keeps them alive with beats.
+type nacosPlugin struct {
+	r       *nacosRegistry
+	group   string
+	network string
+	ip      string
+	port    string
+
+	mu       sync.Mutex
+	services map[string]url.Values
+	stopCh   chan struct{}
+	once     sync.Once
+}
+
+func (p *nacosPlugin) params(service string) url.Values {
+	return url.Values{"serviceName": {service}, "groupName": {p.group}, "ip": {p.ip}, "port": {p.port}, "ephemeral": {"true"}}
+}
+
+func (p *nacosPlugin) Register(name string, rcvr interface{}, metadata string) error {
+	meta, err := url.ParseQuery(metadata)
+	if err != nil {
+		return err
+	}
+	meta.Set(nacosNetworkMetaKey, p.network)
+	m := make(map[string]string, len(meta))
+	for k := range meta {
+		m[k] = meta.Get(k)
+	}
+	b, _ := json.Marshal(m)
+	params := p.params(name)
+	params.Set("metadata", string(b))
+	if _, err := p.r.call(http.MethodPost, "/nacos/v1/ns/instance", params); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.services[name] = meta
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *nacosPlugin) RegisterFunction(serviceName, fname string, fn interface{}, metadata string) error {
+	p.mu.Lock()
+	_, ok := p.services[serviceName]
+	p.mu.Unlock()
+	if ok {
+		return nil
+	}
+	return p.Register(serviceName, fn, metadata)
+}
+
+func (p *nacosPlugin) Unregister(name string) error {
+	p.mu.Lock()
+	delete(p.services, name)
+	p.mu.Unlock()
+	_, err := p.r.call(http.MethodDelete, "/nacos/v1/ns/instance", p.params(name))
+	return err
+}
+
+// Stop deregisters every service and stops the beats.
+func (p *nacosPlugin) Stop() error {
+	p.once.Do(func() { close(p.stopCh) })
+	p.mu.Lock()
+	names := make([]string, 0, len(p.services))
+	for name := range p.services {
+		names = append(names, name)
+	}
+	p.mu.Unlock()
+	var err error
+	for _, name := range names {
+		if e := p.Unregister(name); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (p *nacosPlugin) beat() {
+	defer safe.Defer()
+	ticker := time.NewTicker(nacosBeatInterval)
+	defer ticker.Stop()
+	failures := map[string]*failure{}
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+		p.mu.Lock()
+		services := make(map[string]url.Values, len(p.services))
+		for name, meta := range p.services {
+			services[name] = meta
+		}
+		p.mu.Unlock()
+		for name := range services {
+			port, _ := strconv.Atoi(p.port)
+			beat, _ := json.Marshal(map[string]interface{}{"serviceName": p.group + "@@" + name, "ip": p.ip, "port": port, "scheduled": true})
+			params := p.params(name)
+			params.Set("beat", string(beat))
+			f, ok := failures[name]
+			if !ok {
+				f = &failure{}
+				failures[name] = f
+			}
+			if _, err := p.r.call(http.MethodPut, "/nacos/v1/ns/instance/beat", params); err != nil {
+				f.fail("nacos beat of %s error: %v", name, err)
+			} else {
+				f.ok("nacos beat of %s is back", name)
+			}
+		}
+	}
+}
+
+// nacosDiscovery polls the healthy instances of a service.
+type nacosDiscovery struct {
+	*client.MultipleServersDiscovery
+	r       *nacosRegistry
+	group   string
+	service string
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+func (d *nacosDiscovery) Clone(servicePath string) (client.ServiceDiscovery, error) {
+	return d.r.Discovery(d.group, servicePath, true)
+}
+
+func (d *nacosDiscovery) Close() {
+	d.once.Do(func() { close(d.stopCh) })
+}
+
+func (d *nacosDiscovery) fetch() ([]*client.KVPair, error) {
+	params := url.Values{"serviceName": {d.service}, "groupName": {d.group}, "healthyOnly": {"true"}}
+	b, err := d.r.call(http.MethodGet, "/nacos/v1/ns/instance/list", params)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Hosts []nacosInstance `json:"hosts"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	pairs := make([]*client.KVPair, 0, len(list.Hosts))
+	for _, host := range list.Hosts {
+		if !host.Healthy || !host.Enabled {
+			continue
+		}
+		meta := url.Values{}
+		network := "tcp"
+		for k, v := range host.Metadata {
+			if k == nacosNetworkMetaKey {
+				network = v
+				continue
+			}
+			meta.Set(k, v)
+		}
+		key := network + "@" + net.JoinHostPort(host.Ip, strconv.Itoa(host.Port))
+		pairs = append(pairs, &client.KVPair{Key: key, Value: meta.Encode()})
+	}
+	return pairs, nil
+}
+
+func (d *nacosDiscovery) poll(pairs []*client.KVPair) {
+	defer safe.Defer()
+	interval := d.r.d.UpdateInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var f failure
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+		}
+		next, err := d.fetch()
+		if err != nil {
+			f.fail("nacos discovery of %s error: %v", d.service, err)
+			continue
+		}
+		f.ok("nacos discovery of %s is back", d.service)
+		if !reflect.DeepEqual(next, pairs) {
+			pairs = next
+			d.Update(pairs)
+		}
+	}
+}
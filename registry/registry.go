@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/rpcxio/libkv/store"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/log"
+	"github.com/smallnest/rpcx/server"
+)
+
+const (
+	Etcd      = "etcd"
+	Consul    = "consul"
+	ZooKeeper = "zookeeper"
+	Nacos     = "nacos"
+	Static    = "static"
+	File      = "file"
+)
+
+// Registry registers the services of a server and discovers the servers of a service.
+type Registry interface {
+	// Plugin returns the server plugin registering the services served at addr ("tcp@host:port") under basePath,
+	// nil when the registry needs no registration.
+	Plugin(basePath, addr string) (server.Plugin, error)
+	// Discovery returns the discovery of the servers of service under basePath,
+	// allowMissing allows the service to have no server yet.
+	Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error)
+}
+
+// Factory builds the registry described by d.
+type Factory func(d *types.Discovery) (Registry, error)
+
+var factories = struct {
+	sync.RWMutex
+	m map[string]Factory
+}{m: map[string]Factory{}}
+
+// Register makes the registry of kind available to New, a registry of the same kind is replaced.
+func Register(kind string, f Factory) {
+	factories.Lock()
+	defer factories.Unlock()
+	factories.m[strings.ToLower(kind)] = f
+}
+
+// New builds the registry of d.Type, etcd when it is empty.
+func New(d *types.Discovery) (Registry, error) {
+	kind := strings.ToLower(d.Type)
+	if kind == "" {
+		kind = Etcd
+	}
+	factories.RLock()
+	f, ok := factories.m[kind]
+	factories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("registry %q is not registered", d.Type)
+	}
+	return f(d)
+}
+
+// ServerPlugin returns the plugin registering the services served at addr ("host:port") in the registry of d.
+func ServerPlugin(d *types.Discovery, addr string) (server.Plugin, error) {
+	r, err := New(d)
+	if err != nil {
+		return nil, err
+	}
+	return r.Plugin(d.BasePath, "tcp@"+addr)
+}
+
+// Discovery returns the discovery of service in the registry of d, when d isolates a namespace falling back
+// to the shared one, the servers in the namespace are used as long as there are some.
+func Discovery(d *types.Discovery, service string) (client.ServiceDiscovery, error) {
+	r, err := New(d)
+	if err != nil {
+		return nil, err
+	}
+	if d.SharedBasePath == "" {
+		return r.Discovery(d.BasePath, service, false)
+	}
+	local, err := r.Discovery(d.BasePath, service, true)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := r.Discovery(d.SharedBasePath, service, false)
+	if err != nil {
+		local.Close()
+		return nil, err
+	}
+	return NewFallbackDiscovery(local, shared), nil
+}
+
+// storeConfig returns the kv store options of d, nil when it has none.
+func storeConfig(d *types.Discovery) *store.Config {
+	if d.Username == "" && d.Password == "" {
+		return nil
+	}
+	return &store.Config{Username: d.Username, Password: d.Password}
+}
+
+// failure alerts the first of the consecutive errors of a background task, the next ones and the recovery are
+// only logged so that an outage is alerted once, it is used by one goroutine.
+type failure struct{ failing bool }
+
+func (f *failure) fail(format string, v ...interface{}) {
+	if f.failing {
+		log.Infof(format, v...)
+		return
+	}
+	f.failing = true
+	log.Warnf(format, v...)
+}
+
+func (f *failure) ok(format string, v ...interface{}) {
+	if f.failing {
+		f.failing = false
+		log.Infof(format, v...)
+	}
+}
@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+)
+
+func init() {
+	Register(Static, func(d *types.Discovery) (Registry, error) { return &staticRegistry{d: d}, nil })
+}
+
+// staticRegistry discovers the servers listed by service in the config, nothing is registered.
+type staticRegistry struct{ d *types.Discovery }
+
+func (r *staticRegistry) Plugin(basePath, addr string) (server.Plugin, error) { return nil, nil }
+
+func (r *staticRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	pairs := peerPairs(r.d.Peers[service])
+	if len(pairs) == 0 && !allowMissing {
+		return nil, fmt.Errorf("service %s has no static peer", service)
+	}
+	return client.NewMultipleServersDiscovery(pairs)
+}
+
+// peerPairs converts "tcp@host:port" or "host:port", optionally followed by "?<metadata>", to discovery pairs.
+func peerPairs(peers []string) []*client.KVPair {
+	pairs := make([]*client.KVPair, 0, len(peers))
+	for _, peer := range peers {
+		key, value := peer, ""
+		if i := strings.Index(peer, "?"); i >= 0 {
+			key, value = peer[:i], peer[i+1:]
+		}
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if !strings.Contains(key, "@") {
+			key = "tcp@" + key
+		}
+		pairs = append(pairs, &client.KVPair{Key: key, Value: value})
+	}
+	return pairs
+}
@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/server"
+	"github.com/smallnest/rpcx/serverplugin"
+)
+
+func init() {
+	Register(ZooKeeper, func(d *types.Discovery) (Registry, error) { return &zookeeperRegistry{d: d}, nil })
+}
+
+// zookeeperRegistry keeps the servers as zookeeper nodes, under "<basePath>/<service>/<addr>".
+type zookeeperRegistry struct{ d *types.Discovery }
+
+func (r *zookeeperRegistry) Plugin(basePath, addr string) (server.Plugin, error) {
+	p := &serverplugin.ZooKeeperRegisterPlugin{
+		ServiceAddress:   addr,
+		ZooKeeperServers: r.d.Addr,
+		BasePath:         basePath,
+		UpdateInterval:   r.d.UpdateInterval,
+		Options:          storeConfig(r.d),
+	}
+	return p, p.Start()
+}
+
+func (r *zookeeperRegistry) Discovery(basePath, service string, allowMissing bool) (client.ServiceDiscovery, error) {
+	dis, err := client.NewZookeeperDiscovery(basePath, service, r.d.Addr, storeConfig(r.d))
+	if err != nil && allowMissing {
+		// zookeeper discovery can not watch a missing service node, the service stays unknown until renewed
+		return client.NewMultipleServersDiscovery(nil)
+	}
+	return dis, err
+}
@@ -2,8 +2,12 @@ package framework
 
 import (
 	"errors"
+	"fmt"
+	"time"
+
 	"github.com/ZYallers/golib/utils/logger"
 	errors2 "github.com/ZYallers/rpcx-framework/errors"
+	"github.com/ZYallers/rpcx-framework/helper/redact"
 	"github.com/ZYallers/rpcx-framework/helper/sender"
 	"github.com/ZYallers/rpcx-framework/types"
 	"github.com/go-redis/redis"
@@ -34,8 +38,21 @@ func NewService(options ...types.RpcOption) *types.Rpc {
 		panic("service log dir is empty")
 	}
 
+	if policy := ServiceRedactPolicy(); policy != nil {
+		if err := redact.SetPolicy(policy); err != nil {
+			panic(fmt.Errorf("service redact policy error: %s", err))
+		}
+	}
+	OnChange("service.redact", func(old, new interface{}) {
+		if policy := ServiceRedactPolicy(); policy != nil {
+			_ = redact.SetPolicy(policy)
+		} else {
+			_ = redact.SetPolicy(&redact.Policy{Keys: redact.DefaultKeys})
+		}
+	})
+
 	discovery := ServiceDiscovery()
-	if discovery == nil || discovery.BasePath == "" || len(discovery.Addr) == 0 {
+	if discovery == nil {
 		panic(errors2.ErrServiceDiscoveryNotMeeting)
 	}
 
@@ -45,6 +62,10 @@ func NewService(options ...types.RpcOption) *types.Rpc {
 		HostName:           hostname,
 		SystemIP:           systemIP,
 		LogDir:             serviceLogDir,
+		Log:                ServiceLogConfig(),
+		Access:             ServiceAccessConfig(),
+		Debug:              ServiceDebugConfig(),
+		AdminToken:         ServiceAdminToken(),
 		Addr:               ServiceAddr(),
 		Version:            ServiceVersion(),
 		VersionKey:         ServiceVersionKey(),
@@ -52,6 +73,7 @@ func NewService(options ...types.RpcOption) *types.Rpc {
 		GracefulRobotToken: ServiceGracefulRobotToken(),
 		SqlRobotToken:      ServiceSqlRobotToken(),
 		Etcd:               discovery,
+		Metadata:           serviceMetadata(),
 		Server:             server.NewServer(),
 	}
 
@@ -66,13 +88,33 @@ func NewService(options ...types.RpcOption) *types.Rpc {
 
 func GetRpc() *types.Rpc { return rpc }
 
+// serviceMetadata returns the registry metadata of the node, the handler versions are added on registration.
+func serviceMetadata() *types.Metadata {
+	mc := ServiceMetadataConfig()
+	return &types.Metadata{
+		State:     types.StateActive,
+		Version:   ServiceVersion(),
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		Mode:      ServiceMode(),
+		Weight:    mc.Weight,
+		Zone:      mc.Zone,
+		Group:     mc.Group,
+		StartTime: time.Now(),
+		Tags:      mc.Tags,
+	}
+}
+
 func WithSender() types.RpcOption {
 	return func(s *types.Rpc) error {
+		profile := ServiceModeConfig()
 		message := &types.Message{
 			ErrorToken:    s.ErrorRobotToken,
 			GracefulToken: s.GracefulRobotToken,
 			SqlToken:      s.SqlRobotToken,
 			Mode:          s.Env,
+			AlertAtAll:    profile.AlertAtAll,
+			AlertAlways:   profile.AlertAlways,
 			Name:          s.Name,
 			Addr:          s.Addr,
 			Hostname:      s.HostName,
@@ -82,6 +124,11 @@ func WithSender() types.RpcOption {
 		types.InitMessage(message)
 		s.Sender = message
 		sender.Register(s.Sender)
+		for _, key := range []string{"service.errorRobotToken", "service.gracefulRobotToken", "service.sqlRobotToken"} {
+			OnChange(key, func(_, _ interface{}) {
+				message.SetTokens(ServiceErrorRobotToken(), ServiceGracefulRobotToken(), ServiceSqlRobotToken())
+			})
+		}
 		return nil
 	}
 }
@@ -92,7 +139,12 @@ func WithLogger() types.RpcOption {
 			return errors.New("service log dir is empty")
 		}
 		logger.SetLoggerDir(s.LogDir)
-		log.SetLogger(types.NewLogger(s.Name, s.Sender))
+		log.SetLogger(types.NewLogger(s.Name, s.Sender, s.Log))
+		var revert int
+		if s.Log != nil {
+			revert = s.Log.Revert
+		}
+		types.NotifyLogLevel(time.Duration(revert) * time.Second)
 		return nil
 	}
 }
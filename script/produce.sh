@@ -123,20 +123,22 @@ buildFun(){
 
     echoFun "build runner:" title
     tmpName="${serviceName}_$(date +'%Y%m%d%H%M%S')"
+    # Build info published in the registry metadata
+    buildInfo="-X github.com/ZYallers/rpcx-framework.GitCommit=$(git rev-parse --short HEAD 2>/dev/null) -X github.com/ZYallers/rpcx-framework.BuildTime=$(date +'%Y-%m-%dT%H:%M:%S%z')"
     if [[ "$env" == "debug" ]];then
         echoFun '>>>>>>>>>> build for debug mode <<<<<<<<<<' tip
         # 配合 delve 使用, @see http://wiki.sys.hxsapp.net/pages/viewpage.action?pageId=21349181
-        CGO_ENABLED=0 go build -v -installsuffix cgo -gcflags 'all=-N -l' -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
+        CGO_ENABLED=0 go build -v -installsuffix cgo -gcflags 'all=-N -l' -ldflags "${buildInfo}" -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
     elif [[ "$env" == "dev" ]];then
         echoFun '>>>>>>>>>> build for development mode <<<<<<<<<<' tip
-        CGO_ENABLED=0 go build -v -installsuffix cgo -ldflags '-w' -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
+        CGO_ENABLED=0 go build -v -installsuffix cgo -ldflags "-w ${buildInfo}" -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
     else
         echoFun '>>>>>>>>>> build for production mode <<<<<<<<<<' tip
         # Build compilation parameter reference:
         # Dependency free compilation：https://blog.csdn.net/weixin_42506905/article/details/93135684
         # Detailed explanation of build parameters：https://blog.csdn.net/zl1zl2zl3/article/details/83374131
         # Ldflags parameter：https://blog.csdn.net/javaxflinux/article/details/89177863
-        CGO_ENABLED=0 go build -a -installsuffix cgo -ldflags '-w' -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
+        CGO_ENABLED=0 go build -a -installsuffix cgo -ldflags "-w ${buildInfo}" -i -o ./bin/${tmpName} -tags=jsoniter ./main.go
     fi
 
     if [[ ! -f "./bin/${tmpName}" ]];then
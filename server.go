@@ -1,12 +1,16 @@
 package framework
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"time"
 
 	"github.com/ZYallers/rpcx-framework/helper/restful"
+	"github.com/ZYallers/rpcx-framework/plugin"
+	"github.com/ZYallers/rpcx-framework/registry"
 	"github.com/ZYallers/rpcx-framework/types"
+	"github.com/mitchellh/mapstructure"
 	"github.com/rpcxio/rpcx-etcd/serverplugin"
 	"github.com/smallnest/rpcx/server"
 )
@@ -57,22 +61,85 @@ func WithDisableHTTPGateway(disable bool) types.ServerOption {
 	}
 }
 
+// WithRegistry registers the services of rpc in the registry of the run mode, see `service.registry`,
+// they are deregistered on shutdown.
+func WithRegistry(rpc *types.Rpc) types.ServerOption {
+	return func(s *server.Server) error {
+		p, err := registry.ServerPlugin(rpc.Etcd, rpc.Addr)
+		if err != nil {
+			return fmt.Errorf("%s registry plugin error: %s", rpc.Etcd.Type, err)
+		}
+		if p == nil {
+			return nil
+		}
+		s.Plugins.Add(p)
+		if stopper, ok := p.(interface{ Stop() error }); ok {
+			s.RegisterOnShutdown(func(s *server.Server) { _ = stopper.Stop() })
+		}
+		return nil
+	}
+}
+
+// Deprecated: use WithRegistry, which supports every registry type.
 func WithEtcdV3Plugin(addr string, d *types.Discovery) types.ServerOption {
 	return func(s *server.Server) error {
-		plugin := &serverplugin.EtcdV3RegisterPlugin{
+		p := &serverplugin.EtcdV3RegisterPlugin{
 			ServiceAddress: "tcp@" + addr,
 			EtcdServers:    d.Addr,
 			BasePath:       d.BasePath,
 			UpdateInterval: d.UpdateInterval,
 		}
-		if err := plugin.Start(); err != nil {
+		if err := p.Start(); err != nil {
 			return fmt.Errorf("etcdv3 plugin register error: %s", err)
 		}
-		s.Plugins.Add(plugin)
+		s.Plugins.Add(p)
+		return nil
+	}
+}
+
+// WithAccessLog writes an access log entry for every call when `service.access.enable` is on.
+func WithAccessLog(rpc *types.Rpc) types.ServerOption {
+	return func(s *server.Server) error {
+		if rpc.Access == nil || !rpc.Access.Enable {
+			return nil
+		}
+		s.Plugins.Add(plugin.NewAccessPlugin(rpc.LogDir, rpc.Access))
 		return nil
 	}
 }
 
+// WithDebugPlugin adds the debug plugin configured by `service.debug`, it can be reconfigured at runtime
+// through the adminDebug function or by a config reload.
+func WithDebugPlugin(rpc *types.Rpc) types.ServerOption {
+	return func(s *server.Server) error {
+		p := plugin.NewDebugPlugin(rpc.Debug)
+		s.Plugins.Add(p)
+		OnChange("service.debug", func(old, new interface{}) { p.Update(ServiceDebugConfig()) })
+		return restful.RegisterAdminFunc(rpc, "Debug", debugAdminFunc(p))
+	}
+}
+
+// debugAdminFunc merges the args into the current debug config, `reset` restores the configured one.
+func debugAdminFunc(p *plugin.DebugPlugin) restful.AdminFunc {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if reset, _ := args["reset"].(bool); reset {
+			p.Update(ServiceDebugConfig())
+			return p.Config(), nil
+		}
+		// decoded into a copy, the lists of the config in use and of the loaded one are never written
+		c := p.Config().Clone()
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, ZeroFields: true, Result: c})
+		if err != nil {
+			return nil, err
+		}
+		if err := decoder.Decode(args); err != nil {
+			return nil, fmt.Errorf("debug config error: %s", err)
+		}
+		p.Update(c)
+		return p.Config(), nil
+	}
+}
+
 func WithFunction(rpc *types.Rpc, rest types.Restful) types.ServerOption {
 	return func(s *server.Server) error {
 		if err := restful.RegisterFuncName(rpc, rest); err != nil {
@@ -0,0 +1,24 @@
+package framework
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ZYallers/rpcx-framework/plugin"
+	"github.com/ZYallers/rpcx-framework/types"
+)
+
+func TestDebugAdminFuncCopiesLists(t *testing.T) {
+	loaded := &types.DebugConfig{Enable: true, Hooks: []string{"PostReadRequest", "PostWriteResponse"}}
+	p := plugin.NewDebugPlugin(loaded)
+	if _, err := debugAdminFunc(p)(context.Background(), map[string]interface{}{"hooks": []interface{}{"PreCall"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Config().Hooks; !reflect.DeepEqual(got, []string{"PreCall"}) {
+		t.Fatalf("hooks = %v, want [PreCall]", got)
+	}
+	if want := []string{"PostReadRequest", "PostWriteResponse"}; !reflect.DeepEqual(loaded.Hooks, want) {
+		t.Fatalf("loaded hooks = %v, want %v", loaded.Hooks, want)
+	}
+}
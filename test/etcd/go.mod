@@ -0,0 +1,13 @@
+// The remote config tests against an embedded etcd server. It is a module of its own since the etcd server
+// requires newer grpc and otel versions than the framework is built with, run `go mod tidy` then `go test ./...`.
+module github.com/ZYallers/rpcx-framework/test/etcd
+
+go 1.20
+
+require (
+	github.com/ZYallers/rpcx-framework v0.0.0
+	go.etcd.io/etcd/client/v3 v3.5.10
+	go.etcd.io/etcd/server/v3 v3.5.10
+)
+
+replace github.com/ZYallers/rpcx-framework => ../..
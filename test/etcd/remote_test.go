@@ -0,0 +1,87 @@
+package etcd
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	framework "github.com/ZYallers/rpcx-framework"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+const signExpireKey = "/config/etcdtest/development/service/signExpire"
+
+func startEtcd(t *testing.T) string {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	client, peer := url.URL{Scheme: "http", Host: "127.0.0.1:23790"}, url.URL{Scheme: "http", Host: "127.0.0.1:23800"}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{client}, []url.URL{client}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.InitialCluster = cfg.Name + "=" + peer.String()
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd is not ready")
+	}
+	return client.Host
+}
+
+func TestRemoteConfig(t *testing.T) {
+	addr := startEtcd(t)
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{addr}, DialTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := cli.Put(ctx, signExpireKey, "20"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	conf := `{"global": {"mode": "development"}, "service": {"name": "etcdtest", "addr": "127.0.0.1:18970",
+		"logDir": "` + dir + `", "signExpire": 10, "registry": {"development": {"type": "static"}},
+		"remote": {"enable": true, "addr": "` + addr + `", "timeout": 1}}}`
+	if err := os.WriteFile(filepath.Join(dir, "service.json"), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// ReadInConfig reads the config relative to the file of its caller
+	_, file, _, _ := runtime.Caller(0)
+	rel, err := filepath.Rel(filepath.Dir(file), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	framework.ReadInConfig(rel)
+	if got := framework.Config().Service.SignExpire; got != 20 {
+		t.Fatalf("signExpire = %d, want 20 from etcd", got)
+	}
+	snapshot := filepath.Join(dir, "etcdtest.remote.json")
+	if fi, err := os.Stat(snapshot); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("snapshot %s: %v, %v, want mode 0600", snapshot, fi, err)
+	}
+
+	if err := framework.WatchConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Put(ctx, signExpireKey, "40"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); framework.Config().Service.SignExpire != 40; {
+		if time.Now().After(deadline) {
+			t.Fatalf("signExpire = %d, want 40 after the etcd change", framework.Config().Service.SignExpire)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CheckPassed = "pass"
+	CheckFailed = "fail"
+)
+
+type accessContextKey struct{}
+
+// AccessContextKey stores the *Access of the current request in its share.Context.
+var AccessContextKey = &accessContextKey{}
+
+// AccessConfig is the `service.access` config block.
+type AccessConfig struct {
+	Enable bool      `mapstructure:"enable"`
+	File   string    `mapstructure:"file"`                          // log file name without suffix, default "access"
+	Sample float64   `mapstructure:"sample" validate:"min=0,max=1"` // ratio of successful calls to log, 0 logs all, failed calls are always logged
+	Args   bool      `mapstructure:"args"`                          // log request args
+	Redact []string  `mapstructure:"redact"`                        // arg keys whose values are masked
+	Log    LogConfig `mapstructure:"log"`
+}
+
+// Access is the access log record of one rpc call.
+type Access struct {
+	Start     time.Time
+	Caller    string
+	Transport string
+	Service   string
+	Method    string
+	Version   string
+	Signed    string
+	Logged    string
+	Code      int
+	ReqSize   int
+	RespSize  int
+	Args      map[string]interface{}
+	failed    func(*Access, error)
+}
+
+func AccessFromContext(ctx context.Context) *Access {
+	if ctx == nil {
+		return nil
+	}
+	a, _ := ctx.Value(AccessContextKey).(*Access)
+	return a
+}
+
+func (a *Access) SetHandler(version string, args map[string]interface{}) {
+	if a != nil {
+		a.Version, a.Args = version, args
+	}
+}
+
+func (a *Access) SetSigned(ok bool) {
+	if a != nil {
+		a.Signed = checkResult(ok)
+	}
+}
+
+func (a *Access) SetLogged(ok bool) {
+	if a != nil {
+		a.Logged = checkResult(ok)
+	}
+}
+
+func (a *Access) SetCode(code int) {
+	if a != nil {
+		a.Code = code
+	}
+}
+
+// OnFailed sets fn to log the record when the handler fails,
+// the http gateway does not run the PostWriteResponse hooks of those calls.
+func (a *Access) OnFailed(fn func(*Access, error)) {
+	if a != nil {
+		a.failed = fn
+	}
+}
+
+// Failed logs the record of the call whose handler returned err, if it has a failed hook.
+func (a *Access) Failed(err error) {
+	if a != nil && err != nil && a.failed != nil {
+		a.failed(a, err)
+	}
+}
+
+func checkResult(ok bool) string {
+	if ok {
+		return CheckPassed
+	}
+	return CheckFailed
+}
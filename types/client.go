@@ -0,0 +1,297 @@
+package types
+
+import "strings"
+
+// ClientConfig is the `service.client` block, the profiles of the XClients calling the other services.
+type ClientConfig struct {
+	Default  ClientProfile            `mapstructure:"default"`
+	Services map[string]ClientProfile `mapstructure:"services"`                               // by target service, the unset fields are the default ones
+	Forward  []string                 `mapstructure:"forward"`                                // inbound request metadata passed on by XClientCtx, "request_id" and "trace_id" by default
+	MaxAge   int64                    `mapstructure:"maxAge" default:"3600" validate:"min=0"` // seconds before the clients of a service are replaced, 0 never
+	Report   bool                     `mapstructure:"report"`                                 // sends the breaker and ejection changes to the graceful robot
+}
+
+// ClientProfile configures the XClients of a target service, durations are in milliseconds,
+// unset fields fall back to the default profile, then to the run mode profile and the built-in values.
+type ClientProfile struct {
+	Timeout         int64                    `mapstructure:"timeout" validate:"min=0"`        // of a call, 15000 built in
+	ConnectTimeout  int64                    `mapstructure:"connectTimeout" validate:"min=0"` // 1000 built in
+	Retries         *int                     `mapstructure:"retries" validate:"min=0"`        // 3 built in, the attempts of the retry policy are retries + 1 by default
+	FailMode        string                   `mapstructure:"failMode" validate:"oneof=failover failfast failtry failbackup"`
+	SelectMode      string                   `mapstructure:"selectMode" validate:"oneof=random roundrobin weightedroundrobin weightedicmp consistenthash closest"`
+	SerializeType   string                   `mapstructure:"serializeType" validate:"oneof=msgpack json"` // msgpack built in
+	CompressType    string                   `mapstructure:"compressType" validate:"oneof=none gzip"`     // none built in
+	BackupLatency   int64                    `mapstructure:"backupLatency" validate:"min=0"`              // of failbackup, 10 built in, also the least delay of the hedges
+	BreakerFailures *int                     `mapstructure:"breakerFailures" validate:"min=0"`            // consecutive failures opening the breaker of a method of a server, 10 built in, 0 disables it
+	BreakerWindow   int64                    `mapstructure:"breakerWindow" validate:"min=0"`              // before an open breaker lets the probes through, 10000 built in
+	Selector        SelectorConfig           `mapstructure:"selector"`                                    // replaces selectMode when set
+	Canary          CanaryPolicy             `mapstructure:"canary"`                                      // routes the canary calls to the canary servers, replaces selectMode when set
+	Breaker         BreakerPolicy            `mapstructure:"breaker"`
+	Outlier         OutlierPolicy            `mapstructure:"outlier"`
+	Retry           RetryPolicy              `mapstructure:"retry"`
+	Hedge           HedgePolicy              `mapstructure:"hedge"`
+	Methods         map[string]MethodProfile `mapstructure:"methods"` // by method, e.g. "/user/info"
+}
+
+// SelectorConfig selects the servers by the metadata they publish, see Metadata.
+type SelectorConfig struct {
+	Balance      string `mapstructure:"balance" validate:"oneof=roundrobin random hash"` // weighted by the server weights, roundrobin built in
+	HashKey      string `mapstructure:"hashKey"`                                         // arg of the consistent hashing of the hash balance, e.g. "user_id"
+	ZoneAffinity *bool  `mapstructure:"zoneAffinity"`                                    // prefers the servers of the zone of the caller, `service.metadata.zone`
+	Version      string `mapstructure:"version"`                                         // only calls the servers of the version
+}
+
+// IsSet reports whether s replaces the select mode.
+func (s SelectorConfig) IsSet() bool {
+	return s.Balance != "" || s.HashKey != "" || s.ZoneAffinity != nil && *s.ZoneAffinity || s.Version != ""
+}
+
+// Merge returns s with its unset fields taken from def.
+func (s SelectorConfig) Merge(def SelectorConfig) SelectorConfig {
+	if s.Balance == "" {
+		s.Balance = def.Balance
+	}
+	if s.HashKey == "" {
+		s.HashKey = def.HashKey
+	}
+	if s.ZoneAffinity == nil {
+		s.ZoneAffinity = def.ZoneAffinity
+	}
+	if s.Version == "" {
+		s.Version = def.Version
+	}
+	return s
+}
+
+// CanaryPolicy routes the calls matching a rule or within the percent, and the calls of canary requests, to the
+// servers tagged canary; the other calls go to the other servers. Every call goes to any server when either is missing.
+// The services without policy do not split their servers, canary calls and canary servers included.
+type CanaryPolicy struct {
+	Enabled *bool        `mapstructure:"enabled"`                          // splits the servers without percent nor rules
+	Percent float64      `mapstructure:"percent" validate:"min=0,max=100"` // of the calls, by the hash of the key arg
+	Key     string       `mapstructure:"key"`                              // arg of the percent, "user_id" built in, calls without it are random
+	Rules   []CanaryRule `mapstructure:"rules"`
+}
+
+// CanaryRule matches the calls whose arg is one of the values, e.g. {"arg": "city", "values": ["shenzhen"]}.
+type CanaryRule struct {
+	Arg    string   `mapstructure:"arg"`
+	Values []string `mapstructure:"values"`
+}
+
+// IsSet reports whether c splits the servers.
+func (c CanaryPolicy) IsSet() bool {
+	return c.Enabled != nil && *c.Enabled || c.Percent > 0 || len(c.Rules) > 0
+}
+
+// Merge returns c with its unset fields taken from def.
+func (c CanaryPolicy) Merge(def CanaryPolicy) CanaryPolicy {
+	if c.Enabled == nil {
+		c.Enabled = def.Enabled
+	}
+	if c.Percent == 0 {
+		c.Percent = def.Percent
+	}
+	if c.Key == "" {
+		c.Key = def.Key
+	}
+	if len(c.Rules) == 0 {
+		c.Rules = def.Rules
+	}
+	return c
+}
+
+// BreakerPolicy also opens the breaker of a method of a server on its error or slow call rate. Once open for
+// breakerWindow, it lets Probes calls through and closes if they all succeed. The errors of the handlers do not count.
+type BreakerPolicy struct {
+	Window      int64   `mapstructure:"window" validate:"min=0"`          // of the rates, 10000 built in
+	MinRequests int     `mapstructure:"minRequests" validate:"min=0"`     // in the window before the rates count, 20 built in
+	ErrorRate   float64 `mapstructure:"errorRate" validate:"min=0,max=1"` // 0.5 built in
+	SlowCall    int64   `mapstructure:"slowCall" validate:"min=0"`        // latency of a slow call, none built in
+	SlowRate    float64 `mapstructure:"slowRate" validate:"min=0,max=1"`  // 0.5 built in
+	Probes      int     `mapstructure:"probes" validate:"min=0"`          // 3 built in
+}
+
+// OutlierPolicy ejects a server from the selection after consecutive failures of any of its methods.
+type OutlierPolicy struct {
+	Failures        *int  `mapstructure:"failures" validate:"min=0"`                // 5 built in, 0 disables it
+	EjectTime       int64 `mapstructure:"ejectTime" validate:"min=0"`               // 30000 built in, times the ejections in a row
+	MaxEjectPercent int   `mapstructure:"maxEjectPercent" validate:"min=0,max=100"` // of the servers, 50 built in
+}
+
+// MethodProfile overrides the profile of a service for one of its methods.
+type MethodProfile struct {
+	Timeout int64       `mapstructure:"timeout" validate:"min=0"`
+	Retry   RetryPolicy `mapstructure:"retry"`
+	Hedge   HedgePolicy `mapstructure:"hedge"`
+}
+
+// HedgePolicy sends a second request of an idempotent call to another server when the first one is slower than the
+// percentile of the latencies of the method, the slower request is canceled.
+type HedgePolicy struct {
+	Enabled    *bool   `mapstructure:"enabled"`
+	Percentile float64 `mapstructure:"percentile" validate:"min=0,max=100"` // 95 built in
+	Delay      int64   `mapstructure:"delay" validate:"min=0"`              // at least, and until the latencies are known, backupLatency built in
+	Budget     float64 `mapstructure:"budget" validate:"min=0,max=1"`       // hedges per call at most, 0.1 built in
+}
+
+// Merge returns h with its unset fields taken from def.
+func (h HedgePolicy) Merge(def HedgePolicy) HedgePolicy {
+	if h.Enabled == nil {
+		h.Enabled = def.Enabled
+	}
+	if h.Percentile == 0 {
+		h.Percentile = def.Percentile
+	}
+	if h.Delay == 0 {
+		h.Delay = def.Delay
+	}
+	if h.Budget == 0 {
+		h.Budget = def.Budget
+	}
+	return h
+}
+
+// RetryPolicy retries the failed calls with an exponential backoff. The calls which were not sent (unavailable)
+// are always retried, the other ones only when the servers declare the method idempotent or Idempotent says so.
+type RetryPolicy struct {
+	MaxAttempts    int      `mapstructure:"maxAttempts" validate:"min=0"`    // including the first one
+	Backoff        int64    `mapstructure:"backoff" validate:"min=0"`        // before the first retry then doubled, 50 built in
+	MaxBackoff     int64    `mapstructure:"maxBackoff" validate:"min=0"`     // 1000 built in
+	Jitter         float64  `mapstructure:"jitter" validate:"min=0,max=1"`   // random part of the backoff, 0.2 built in
+	AttemptTimeout int64    `mapstructure:"attemptTimeout" validate:"min=0"` // of every attempt within the call timeout
+	RetryOn        []string `mapstructure:"retryOn"`                         // timeout, network, server; timeout and network built in
+	Idempotent     *bool    `mapstructure:"idempotent"`                      // overrides what the servers declare
+}
+
+// Merge returns p with its unset fields taken from def.
+func (p RetryPolicy) Merge(def RetryPolicy) RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.Backoff == 0 {
+		p.Backoff = def.Backoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Jitter == 0 {
+		p.Jitter = def.Jitter
+	}
+	if p.AttemptTimeout == 0 {
+		p.AttemptTimeout = def.AttemptTimeout
+	}
+	if len(p.RetryOn) == 0 {
+		p.RetryOn = def.RetryOn
+	}
+	if p.Idempotent == nil {
+		p.Idempotent = def.Idempotent
+	}
+	return p
+}
+
+// Merge returns p with its unset fields taken from def.
+func (p ClientProfile) Merge(def ClientProfile) ClientProfile {
+	if p.Timeout == 0 {
+		p.Timeout = def.Timeout
+	}
+	if p.ConnectTimeout == 0 {
+		p.ConnectTimeout = def.ConnectTimeout
+	}
+	if p.Retries == nil {
+		p.Retries = def.Retries
+	}
+	if p.FailMode == "" {
+		p.FailMode = def.FailMode
+	}
+	if p.SelectMode == "" {
+		p.SelectMode = def.SelectMode
+	}
+	if p.SerializeType == "" {
+		p.SerializeType = def.SerializeType
+	}
+	if p.CompressType == "" {
+		p.CompressType = def.CompressType
+	}
+	if p.BackupLatency == 0 {
+		p.BackupLatency = def.BackupLatency
+	}
+	if p.BreakerFailures == nil {
+		p.BreakerFailures = def.BreakerFailures
+	}
+	if p.BreakerWindow == 0 {
+		p.BreakerWindow = def.BreakerWindow
+	}
+	p.Selector = p.Selector.Merge(def.Selector)
+	p.Canary = p.Canary.Merge(def.Canary)
+	if p.Breaker.Window == 0 {
+		p.Breaker.Window = def.Breaker.Window
+	}
+	if p.Breaker.MinRequests == 0 {
+		p.Breaker.MinRequests = def.Breaker.MinRequests
+	}
+	if p.Breaker.ErrorRate == 0 {
+		p.Breaker.ErrorRate = def.Breaker.ErrorRate
+	}
+	if p.Breaker.SlowCall == 0 {
+		p.Breaker.SlowCall = def.Breaker.SlowCall
+	}
+	if p.Breaker.SlowRate == 0 {
+		p.Breaker.SlowRate = def.Breaker.SlowRate
+	}
+	if p.Breaker.Probes == 0 {
+		p.Breaker.Probes = def.Breaker.Probes
+	}
+	if p.Outlier.Failures == nil {
+		p.Outlier.Failures = def.Outlier.Failures
+	}
+	if p.Outlier.EjectTime == 0 {
+		p.Outlier.EjectTime = def.Outlier.EjectTime
+	}
+	if p.Outlier.MaxEjectPercent == 0 {
+		p.Outlier.MaxEjectPercent = def.Outlier.MaxEjectPercent
+	}
+	p.Retry = p.Retry.Merge(def.Retry)
+	p.Hedge = p.Hedge.Merge(def.Hedge)
+	if len(def.Methods) > 0 {
+		methods := make(map[string]MethodProfile, len(def.Methods)+len(p.Methods))
+		for name, m := range def.Methods {
+			methods[name] = m
+		}
+		for name, m := range p.Methods {
+			methods[name] = m
+		}
+		p.Methods = methods
+	}
+	return p
+}
+
+// ClientProfile returns the profile of the XClients calling service.
+func (c *Config) ClientProfile(service string) ClientProfile {
+	return lookupProfile(c.Service.Client.Services, service).Merge(c.Service.Client.Default)
+}
+
+// MethodProfile returns the profile of the calls of method of service.
+func (c *Config) MethodProfile(service, method string) ClientProfile {
+	p := c.ClientProfile(service)
+	m, ok := p.Methods[method]
+	if !ok {
+		m, ok = p.Methods[strings.ToLower(method)]
+	}
+	if ok {
+		if m.Timeout > 0 {
+			p.Timeout = m.Timeout
+		}
+		p.Retry = m.Retry.Merge(p.Retry)
+		p.Hedge = m.Hedge.Merge(p.Hedge)
+	}
+	return p
+}
+
+// lookupProfile finds the profile of service, the config keys are lower cased.
+func lookupProfile(profiles map[string]ClientProfile, service string) ClientProfile {
+	if p, ok := profiles[service]; ok {
+		return p
+	}
+	return profiles[strings.ToLower(service)]
+}
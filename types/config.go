@@ -0,0 +1,102 @@
+package types
+
+import (
+	"sync/atomic"
+
+	"github.com/ZYallers/rpcx-framework/helper/redact"
+	"github.com/ZYallers/rpcx-framework/helper/schema"
+)
+
+// Config is the typed service.json, loaded and validated once by framework.ReadInConfig.
+type Config struct {
+	Global  GlobalConfig  `mapstructure:"global"`
+	Service ServiceConfig `mapstructure:"service"`
+}
+
+type GlobalConfig struct {
+	Mode          string                      `mapstructure:"mode"`          // run mode, takes precedence over modeKey
+	ModeKey       string                      `mapstructure:"modeKey"`       // env var holding the run mode, development when it is empty
+	SecretKeyFile string                      `mapstructure:"secretKeyFile"` // AES-GCM key decrypting the enc:// values, made by cmd/secret
+	Server        map[string]GlobalServerNode `mapstructure:"server"`
+	Modes         map[string]ModeConfig       `mapstructure:"modes"` // profiles of the run modes, see DefaultModes
+}
+
+type GlobalServerNode struct {
+	Hostname string `mapstructure:"hostname"`
+	Ip       string `mapstructure:"ip"`
+}
+
+type ServiceConfig struct {
+	Name               string                    `mapstructure:"name" validate:"required"`
+	Addr               string                    `mapstructure:"addr" validate:"required,hostport"`
+	LogDir             string                    `mapstructure:"logDir" validate:"required"`
+	Log                *LogConfig                `mapstructure:"log"`
+	Access             *AccessConfig             `mapstructure:"access"`
+	Redact             *redact.Policy            `mapstructure:"redact"`
+	Debug              *DebugConfig              `mapstructure:"debug"`
+	AdminToken         string                    `mapstructure:"adminToken" redact:"true"`
+	Version            string                    `mapstructure:"version"`
+	VersionKey         string                    `mapstructure:"versionKey" default:"app_version"`
+	TokenKey           string                    `mapstructure:"tokenKey" default:"sess_token"`
+	SignKey            string                    `mapstructure:"signKey" default:"sign"`
+	SignExpire         int64                     `mapstructure:"signExpire" default:"60" validate:"min=1"`
+	SignSecret         string                    `mapstructure:"signSecret" redact:"true"`
+	TimeKey            string                    `mapstructure:"timeKey" default:"utime"`
+	SessionKeyPrefix   string                    `mapstructure:"sessionKeyPrefix" default:"ci_session:"`
+	DebugValue         string                    `mapstructure:"debugValue"`
+	ErrorRobotToken    string                    `mapstructure:"errorRobotToken" redact:"true"`
+	GracefulRobotToken string                    `mapstructure:"gracefulRobotToken" redact:"true"`
+	SqlRobotToken      string                    `mapstructure:"sqlRobotToken" redact:"true"`
+	Registry           map[string]RegistryConfig `mapstructure:"registry"`
+	Etcd               map[string]EtcdConfig     `mapstructure:"etcd"` // registries of the modes missing in Registry
+	Remote             *RemoteConfig             `mapstructure:"remote"`
+	Metadata           MetadataConfig            `mapstructure:"metadata"`
+	Client             ClientConfig              `mapstructure:"client"`
+}
+
+// RegistryConfig is the `service.registry.<mode>` block, the servers of a service are registered and
+// discovered under "<basePath>/<service>" in etcd, consul, zookeeper or nacos (as group), listed in peers
+// by the static registry or read from a JSON or YAML file by the file one.
+type RegistryConfig struct {
+	Type           string              `mapstructure:"type" default:"etcd" validate:"oneof=etcd consul zookeeper nacos static file"`
+	BasePath       string              `mapstructure:"basePath"`
+	Addr           string              `mapstructure:"addr"` // comma separated servers, "$NAME" reads them from the env var
+	UpdateInterval int64               `mapstructure:"updateInterval" default:"30" validate:"min=1"`
+	Username       string              `mapstructure:"username"`
+	Password       string              `mapstructure:"password" redact:"true"`
+	Peers          map[string][]string `mapstructure:"peers"`    // static: "tcp@host:port?<metadata>" of every service
+	File           string              `mapstructure:"file"`     // file: service name to peers, like peers
+	Register       bool                `mapstructure:"register"` // file: the servers add their address to the file
+}
+
+// EtcdConfig is the legacy `service.etcd.<mode>` block.
+type EtcdConfig = RegistryConfig
+
+// RemoteConfig is the `service.remote` block, the keys under "<prefix>/<service name>/<mode>/" in etcd
+// are merged over the local file, e.g. "/config/rpcx-example/production/service/signExpire" = "120".
+type RemoteConfig struct {
+	Enable   bool   `mapstructure:"enable"`
+	Prefix   string `mapstructure:"prefix" default:"/config"`
+	Addr     string `mapstructure:"addr"`                                 // comma separated etcd endpoints, the discovery etcd of the mode when empty
+	Snapshot string `mapstructure:"snapshot"`                             // local copy used when etcd is unavailable, "<logDir>/<name>.remote.json" when empty
+	Timeout  int64  `mapstructure:"timeout" default:"5" validate:"min=1"` // seconds
+}
+
+var config atomic.Value
+
+// NewConfig returns a config holding only the defaults.
+func NewConfig() *Config {
+	c := &Config{}
+	schema.Defaults(c)
+	return c
+}
+
+func StoreConfig(c *Config) { config.Store(c) }
+
+// GetConfig returns the config stored by the last (re)load, or the defaults before the first one.
+func GetConfig() *Config {
+	if c, ok := config.Load().(*Config); ok {
+		return c
+	}
+	return NewConfig()
+}
@@ -0,0 +1,25 @@
+package types
+
+// DebugConfig is the `service.debug` config block of the debug plugin.
+type DebugConfig struct {
+	Enable       bool     `mapstructure:"enable" json:"enable"`
+	Level        string   `mapstructure:"level" json:"level" validate:"oneof=debug info"` // debug or info, default debug
+	Hooks        []string `mapstructure:"hooks" json:"hooks"`                             // plugin hooks to log, empty logs every hook
+	MaxLength    int      `mapstructure:"maxLength" json:"maxLength" validate:"min=0"`    // printed payloads are truncated to it, default 256
+	Services     []string `mapstructure:"services" json:"services"`                       // only log these service paths
+	Methods      []string `mapstructure:"methods" json:"methods"`                         // only log these service methods
+	Callers      []string `mapstructure:"callers" json:"callers"`                         // only log requests from these ip or ip:port
+	RequestIds   []string `mapstructure:"requestIds" json:"requestIds"`                   // only log requests with these ids
+	RequestIdKey string   `mapstructure:"requestIdKey" json:"requestIdKey"`               // request metadata key holding the id, default "request_id"
+}
+
+// Clone returns a deep copy of c, its lists are not shared.
+func (c *DebugConfig) Clone() *DebugConfig {
+	cp := *c
+	for _, list := range []*[]string{&cp.Hooks, &cp.Services, &cp.Methods, &cp.Callers, &cp.RequestIds} {
+		if *list != nil {
+			*list = append([]string(nil), *list...)
+		}
+	}
+	return &cp
+}
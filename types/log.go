@@ -0,0 +1,216 @@
+package types
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natefinch/lumberjack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	HourlyRotate = "hourly"
+	DailyRotate  = "daily"
+)
+
+// LogConfig is the `service.log` config block.
+type LogConfig struct {
+	Level      string `mapstructure:"level" validate:"oneof=debug info warn error dpanic panic fatal"` // default debug
+	Encoding   string `mapstructure:"encoding" validate:"oneof=json console"`                          // default json
+	MaxSize    int    `mapstructure:"maxSize" validate:"min=0"`                                        // megabytes before the file is rotated, default 100
+	MaxAge     int    `mapstructure:"maxAge" validate:"min=0"`                                         // days to retain rotated files, 0 retains all
+	MaxBackups int    `mapstructure:"maxBackups" validate:"min=0"`                                     // number of rotated files to retain, 0 retains all
+	Rotate     string `mapstructure:"rotate" validate:"oneof=hourly daily"`                            // additional time based rotation
+	Compress   bool   `mapstructure:"compress"`                                                        // gzip rotated files
+	Split      bool   `mapstructure:"split"`                                                           // write debug, info, warn and error into separate files
+	Stdout     bool   `mapstructure:"stdout"`                                                          // also write to stdout, useful in containers
+	Revert     int    `mapstructure:"revert" validate:"min=0"`                                         // seconds after which a level raised by SIGUSR1 reverts, 0 never reverts
+}
+
+var (
+	splitLevels = []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+	logLevels   sync.Map
+)
+
+// logLevel is the runtime adjustable level of a named logger.
+type logLevel struct {
+	zap.AtomicLevel
+	initial zapcore.Level
+	mu      sync.Mutex
+	timer   *time.Timer
+}
+
+// NewZapLogger builds a zap logger writing to "<dir>/<name>.log", or to
+// "<dir>/<name>.<level>.log" when c.Split is on.
+func NewZapLogger(dir, name string, c *LogConfig) *zap.Logger {
+	if c == nil {
+		c = &LogConfig{}
+	}
+	if dir == "" {
+		dir, _ = filepath.Abs(".")
+	}
+	initial := ParseLogLevel(c.Level)
+	level := zap.NewAtomicLevelAt(initial)
+	logLevels.Store(name, &logLevel{AtomicLevel: level, initial: initial})
+	encoder := newLogEncoder(c.Encoding)
+
+	var cores []zapcore.Core
+	if c.Split {
+		for _, lv := range splitLevels {
+			file := filepath.Join(dir, name+"."+lv.String()+".log")
+			cores = append(cores, zapcore.NewCore(encoder, newLogWriter(file, c), splitLevelEnabler(level, lv)))
+		}
+	} else {
+		cores = append(cores, zapcore.NewCore(encoder, newLogWriter(filepath.Join(dir, name+".log"), c), level))
+	}
+	if c.Stdout {
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), level))
+	}
+	return zap.New(zapcore.NewTee(cores...), zap.AddCaller())
+}
+
+// SetLogLevel changes the level of the named logger, or of every logger when name is empty,
+// the initial level is restored after revert when it is positive.
+func SetLogLevel(name, level string, revert time.Duration) error {
+	var lv zapcore.Level
+	if err := lv.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return err
+	}
+	found := false
+	logLevels.Range(func(key, value interface{}) bool {
+		if name == "" || key.(string) == name {
+			found = true
+			value.(*logLevel).set(lv, revert)
+		}
+		return true
+	})
+	if !found {
+		return fmt.Errorf("logger %q not found", name)
+	}
+	return nil
+}
+
+// ResetLogLevel restores the initial level of the named logger, or of every logger when name is empty.
+func ResetLogLevel(name string) {
+	logLevels.Range(func(key, value interface{}) bool {
+		if name == "" || key.(string) == name {
+			l := value.(*logLevel)
+			l.set(l.initial, 0)
+		}
+		return true
+	})
+}
+
+// LogLevels returns the current level of every named logger.
+func LogLevels() map[string]string {
+	levels := map[string]string{}
+	logLevels.Range(func(key, value interface{}) bool {
+		levels[key.(string)] = value.(*logLevel).Level().String()
+		return true
+	})
+	return levels
+}
+
+func (l *logLevel) set(lv zapcore.Level, revert time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.SetLevel(lv)
+	if revert > 0 && lv != l.initial {
+		l.timer = time.AfterFunc(revert, func() { l.set(l.initial, 0) })
+	}
+}
+
+func ParseLogLevel(s string) zapcore.Level {
+	var lv zapcore.Level
+	if err := lv.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+		return zapcore.DebugLevel
+	}
+	return lv
+}
+
+// splitLevelEnabler lets a core accept exactly one level, error file takes every level above error too.
+func splitLevelEnabler(level zap.AtomicLevel, lv zapcore.Level) zap.LevelEnablerFunc {
+	return func(l zapcore.Level) bool {
+		if !level.Enabled(l) {
+			return false
+		}
+		if lv == zapcore.ErrorLevel {
+			return l >= lv
+		}
+		return l == lv
+	}
+}
+
+func newLogEncoder(encoding string) zapcore.Encoder {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeTime: func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(t.Format("2006/01/02 15:04:05.000"))
+		},
+	}
+	if encoding == "console" {
+		cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(cfg)
+	}
+	return zapcore.NewJSONEncoder(cfg)
+}
+
+func newLogWriter(file string, c *LogConfig) zapcore.WriteSyncer {
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+	lk := &lumberjack.Logger{Filename: file, MaxSize: maxSize, MaxAge: c.MaxAge,
+		MaxBackups: c.MaxBackups, LocalTime: true, Compress: c.Compress}
+	switch c.Rotate {
+	case HourlyRotate, DailyRotate:
+		return zapcore.AddSync(&rotateWriter{Logger: lk, rotate: c.Rotate})
+	}
+	return zapcore.AddSync(lk)
+}
+
+// rotateWriter rotates the underlying lumberjack file on every hour or day boundary.
+type rotateWriter struct {
+	*lumberjack.Logger
+	mu     sync.Mutex
+	rotate string
+	next   time.Time
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if now := time.Now(); !now.Before(w.next) {
+		if !w.next.IsZero() {
+			_ = w.Logger.Rotate()
+		}
+		w.next = nextRotateTime(now, w.rotate)
+	}
+	w.mu.Unlock()
+	return w.Logger.Write(p)
+}
+
+func nextRotateTime(now time.Time, rotate string) time.Time {
+	y, m, d := now.Date()
+	if rotate == HourlyRotate {
+		return time.Date(y, m, d, now.Hour()+1, 0, 0, 0, now.Location())
+	}
+	return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
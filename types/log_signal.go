@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package types
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// NotifyLogLevel raises every logger to debug on SIGUSR1 and restores the initial levels on SIGUSR2.
+func NotifyLogLevel(revert time.Duration) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range ch {
+			if sig == syscall.SIGUSR1 {
+				_ = SetLogLevel("", zapcore.DebugLevel.String(), revert)
+			} else {
+				ResetLogLevel("")
+			}
+		}
+	}()
+}
@@ -0,0 +1,6 @@
+package types
+
+import "time"
+
+// NotifyLogLevel is a no-op, windows has no SIGUSR1 and SIGUSR2.
+func NotifyLogLevel(revert time.Duration) {}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	libLogger "github.com/ZYallers/golib/utils/logger"
+	"github.com/smallnest/rpcx/log"
+)
+
+func TestNewZapLoggerCaller(t *testing.T) {
+	dir := t.TempDir()
+	zl := NewZapLogger(dir, "caller", &LogConfig{Level: "info"})
+	zl.Info("hello")
+	_ = zl.Sync()
+	if caller := readCaller(t, filepath.Join(dir, "caller.log")); !strings.HasPrefix(caller, "types/log_test.go:") {
+		t.Fatalf("caller = %q, want types/log_test.go", caller)
+	}
+}
+
+func TestNewLoggerCaller(t *testing.T) {
+	dir := t.TempDir()
+	libLogger.SetLoggerDir(dir)
+	log.SetLogger(NewLogger("wrapped", nil, &LogConfig{Level: "info"}))
+	log.Info("hello")
+	if caller := readCaller(t, filepath.Join(dir, "wrapped.log")); !strings.HasPrefix(caller, "types/log_test.go:") {
+		t.Fatalf("caller = %q, want types/log_test.go", caller)
+	}
+}
+
+func readCaller(t *testing.T, file string) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry struct {
+		Caller string `json:"caller"`
+	}
+	if err := json.Unmarshal(b[:strings.IndexByte(string(b), '\n')], &entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry.Caller
+}
@@ -5,6 +5,7 @@ import (
 	"runtime/debug"
 
 	libLogger "github.com/ZYallers/golib/utils/logger"
+	"github.com/ZYallers/rpcx-framework/helper/redact"
 	"go.uber.org/zap"
 )
 
@@ -13,36 +14,48 @@ type logger struct {
 	handler func() *zap.Logger
 }
 
-func NewLogger(name string, sender Sender) *logger {
+// NewLogger writes into "<logger dir>/<name>.log" by the golib logger, or by a zap logger of config whose level
+// can be changed at runtime by SetLogLevel.
+func NewLogger(name string, sender Sender, config ...*LogConfig) *logger {
+	if len(config) == 0 || config[0] == nil {
+		return &logger{
+			Sender: sender,
+			handler: func() *zap.Logger {
+				return libLogger.Use(name)
+			},
+		}
+	}
+	// the caller is the one of the rpcx log functions wrapping the logger
+	zl := NewZapLogger(libLogger.GetLoggerDir(), name, config[0]).WithOptions(zap.AddCallerSkip(2))
 	return &logger{
 		Sender: sender,
 		handler: func() *zap.Logger {
-			return libLogger.Use(name)
+			return zl
 		},
 	}
 }
 
 func (l *logger) Debug(v ...interface{}) {
-	l.handler().Debug(fmt.Sprint(v...))
+	l.handler().Debug(redact.String(fmt.Sprint(v...)))
 }
 
 func (l *logger) Debugf(format string, v ...interface{}) {
-	l.handler().Debug(fmt.Sprintf(format, v...))
+	l.handler().Debug(redact.String(fmt.Sprintf(format, v...)))
 }
 
 func (l *logger) Info(v ...interface{}) {
-	l.handler().Info(fmt.Sprint(v...))
+	l.handler().Info(redact.String(fmt.Sprint(v...)))
 }
 
 func (l *logger) Infof(format string, v ...interface{}) {
 	if format == "client has closed this connection: %s" {
 		return
 	}
-	l.handler().Info(fmt.Sprintf(format, v...))
+	l.handler().Info(redact.String(fmt.Sprintf(format, v...)))
 }
 
 func (l *logger) Warn(v ...interface{}) {
-	s := fmt.Sprint(v...)
+	s := redact.String(fmt.Sprint(v...))
 	l.handler().Warn(s)
 	if l.Sender != nil {
 		l.Sender.Error("Warn: "+s, string(debug.Stack()), true)
@@ -50,7 +63,7 @@ func (l *logger) Warn(v ...interface{}) {
 }
 
 func (l *logger) Warnf(format string, v ...interface{}) {
-	s := fmt.Sprintf(format, v...)
+	s := redact.String(fmt.Sprintf(format, v...))
 	l.handler().Warn(s)
 	if l.Sender != nil {
 		l.Sender.Error("Warn: "+s, string(debug.Stack()), true)
@@ -58,7 +71,7 @@ func (l *logger) Warnf(format string, v ...interface{}) {
 }
 
 func (l *logger) Error(v ...interface{}) {
-	s := fmt.Sprint(v...)
+	s := redact.String(fmt.Sprint(v...))
 	l.handler().Error(s)
 	if l.Sender != nil {
 		l.Sender.Error("Error: "+s, string(debug.Stack()), true)
@@ -66,7 +79,7 @@ func (l *logger) Error(v ...interface{}) {
 }
 
 func (l *logger) Errorf(format string, v ...interface{}) {
-	s := fmt.Sprintf(format, v...)
+	s := redact.String(fmt.Sprintf(format, v...))
 	l.handler().Error(s)
 	if l.Sender != nil {
 		l.Sender.Error("Error: "+s, string(debug.Stack()), true)
@@ -74,7 +87,7 @@ func (l *logger) Errorf(format string, v ...interface{}) {
 }
 
 func (l *logger) Fatal(v ...interface{}) {
-	s := fmt.Sprint(v...)
+	s := redact.String(fmt.Sprint(v...))
 	l.handler().Fatal(s)
 	if l.Sender != nil {
 		l.Sender.Error("Fatal: "+s, string(debug.Stack()), true)
@@ -82,7 +95,7 @@ func (l *logger) Fatal(v ...interface{}) {
 }
 
 func (l *logger) Fatalf(format string, v ...interface{}) {
-	s := fmt.Sprintf(format, v...)
+	s := redact.String(fmt.Sprintf(format, v...))
 	l.handler().Fatal(s)
 	if l.Sender != nil {
 		l.Sender.Error("Fatal: "+s, string(debug.Stack()), true)
@@ -90,7 +103,7 @@ func (l *logger) Fatalf(format string, v ...interface{}) {
 }
 
 func (l *logger) Panic(v ...interface{}) {
-	s := fmt.Sprint(v...)
+	s := redact.String(fmt.Sprint(v...))
 	l.handler().Panic(s)
 	if l.Sender != nil {
 		l.Sender.Error("Panic: "+s, string(debug.Stack()), true)
@@ -98,7 +111,7 @@ func (l *logger) Panic(v ...interface{}) {
 }
 
 func (l *logger) Panicf(format string, v ...interface{}) {
-	s := fmt.Sprintf(format, v...)
+	s := redact.String(fmt.Sprintf(format, v...))
 	l.handler().Panic(s)
 	if l.Sender != nil {
 		l.Sender.Error("Panic: "+s, string(debug.Stack()), true)
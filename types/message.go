@@ -3,10 +3,11 @@ package types
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ZYallers/golib/utils/curl"
-	"github.com/ZYallers/rpcx-framework/consts"
+	"github.com/ZYallers/rpcx-framework/helper/redact"
 	"github.com/smallnest/rpcx/log"
 )
 
@@ -25,32 +26,48 @@ type Message struct {
 	GracefulToken string
 	SqlToken      string
 	Mode          string
+	AlertAtAll    bool
+	AlertAlways   bool
 	Name          string
 	Addr          string
 	Hostname      string
 	SystemIP      string
 	PublicIP      string
+	mu            sync.RWMutex // of the tokens, swapped by SetTokens
 }
 
 func InitMessage(m *Message)    { message = m }
 func GetMessage() *Message      { return message }
 func (s *Message) Open() bool   { return s != nil }
-func (s *Message) Always() bool { return s != nil && s.Mode == consts.DevelopMode }
+func (s *Message) Always() bool { return s != nil && s.AlertAlways }
 func (s *Message) Push(msg string) {
 	if s != nil {
-		s.Send(s.SqlToken, msg, true)
+		s.Send(s.token(&s.SqlToken), msg, true)
 	}
 }
 
+// SetTokens replaces the robot tokens, e.g. on a config reload.
+func (s *Message) SetTokens(errorToken, gracefulToken, sqlToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ErrorToken, s.GracefulToken, s.SqlToken = errorToken, gracefulToken, sqlToken
+}
+
+func (s *Message) token(t *string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return *t
+}
+
 func (s *Message) Graceful(msg interface{}, isAtAll bool, logType ...interface{}) {
 	if s != nil {
-		s.Send(s.GracefulToken, msg, append([]interface{}{"", isAtAll}, logType...)...)
+		s.Send(s.token(&s.GracefulToken), msg, append([]interface{}{"", isAtAll}, logType...)...)
 	}
 }
 
 func (s *Message) Error(msg interface{}, stack string, isAtAll bool, logType ...interface{}) {
 	if s != nil {
-		s.Send(s.ErrorToken, msg, append([]interface{}{stack, isAtAll}, logType...)...)
+		s.Send(s.token(&s.ErrorToken), msg, append([]interface{}{stack, isAtAll}, logType...)...)
 	}
 }
 
@@ -59,7 +76,7 @@ func (s *Message) Send(token string, msg interface{}, options ...interface{}) {
 	if s == nil {
 		return
 	}
-	title := fmt.Sprintf("%v", msg)
+	title := redact.String(fmt.Sprintf("%v", msg))
 	if token == "" || title == "" {
 		return
 	}
@@ -75,11 +92,11 @@ func (s *Message) Send(token string, msg interface{}, options ...interface{}) {
 	}
 	if len(options) > 0 {
 		if stack, ok := options[0].(string); ok && stack != "" {
-			text = append(text, "\nStack:\n"+stack)
+			text = append(text, "\nStack:\n"+redact.String(stack))
 		}
 	}
 	var isAtAll bool
-	if s.Mode == consts.ProduceMode && len(options) > 1 {
+	if s.AlertAtAll && len(options) > 1 {
 		if val, ok := options[1].(bool); ok {
 			isAtAll = val
 		}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata keys published by the nodes, state, group and weight are also read by rpcx.
+const (
+	MetaState      = "state"
+	MetaVersion    = "version"
+	MetaGitCommit  = "git_commit"
+	MetaBuildTime  = "build_time"
+	MetaMode       = "mode"
+	MetaWeight     = "weight"
+	MetaZone       = "zone"
+	MetaGroup      = "group"
+	MetaHandlers   = "handlers"
+	MetaIdempotent = "idempotent"
+	MetaStartTime  = "start_time"
+	MetaCanary     = "canary" // tag of the canary nodes, "true"
+
+	StateActive = "active"
+)
+
+// MetadataConfig is the `service.metadata` block, published with the build info by every node of the service.
+type MetadataConfig struct {
+	Weight int               `mapstructure:"weight" default:"1" validate:"min=0"` // weighted round robin weight
+	Zone   string            `mapstructure:"zone"`
+	Group  string            `mapstructure:"group"` // rpcx only calls the nodes of the group of the client, if any
+	Tags   map[string]string `mapstructure:"tags"`  // more metadata, e.g. {"canary": "true"}
+}
+
+// Metadata is what a node publishes about itself in the registry, encoded as a url query,
+// e.g. "state=active&version=1.2.0&weight=1&zone=sz-a&handlers=1.0.0,1.2.0&start_time=1700000000".
+type Metadata struct {
+	State      string
+	Version    string
+	GitCommit  string
+	BuildTime  string
+	Mode       string
+	Weight     int
+	Zone       string
+	Group      string
+	Handlers   []string // versions of the handlers served, sorted
+	Idempotent []string // paths whose handlers are all idempotent, which clients may retry, sorted
+	StartTime  time.Time
+	Tags       map[string]string
+}
+
+// String encodes m, the tags never replace the other keys.
+func (m *Metadata) String() string {
+	v := url.Values{}
+	for k, tag := range m.Tags {
+		v.Set(k, tag)
+	}
+	set := func(k, s string) {
+		if s != "" {
+			v.Set(k, s)
+		} else {
+			v.Del(k)
+		}
+	}
+	set(MetaState, m.State)
+	set(MetaVersion, m.Version)
+	set(MetaGitCommit, m.GitCommit)
+	set(MetaBuildTime, m.BuildTime)
+	set(MetaMode, m.Mode)
+	set(MetaWeight, strconv.Itoa(m.Weight))
+	set(MetaZone, m.Zone)
+	set(MetaGroup, m.Group)
+	set(MetaHandlers, strings.Join(m.Handlers, ","))
+	set(MetaIdempotent, strings.Join(m.Idempotent, ","))
+	if !m.StartTime.IsZero() {
+		set(MetaStartTime, strconv.FormatInt(m.StartTime.Unix(), 10))
+	}
+	return v.Encode()
+}
+
+// SetHandlers sets the distinct handler versions.
+func (m *Metadata) SetHandlers(versions ...string) {
+	seen := make(map[string]bool, len(versions))
+	m.Handlers = m.Handlers[:0]
+	for _, ver := range versions {
+		if ver != "" && !seen[ver] {
+			seen[ver] = true
+			m.Handlers = append(m.Handlers, ver)
+		}
+	}
+	sort.Strings(m.Handlers)
+}
+
+// IsIdempotent reports whether the node declares the handlers of path idempotent.
+func (m *Metadata) IsIdempotent(path string) bool {
+	i := sort.SearchStrings(m.Idempotent, path)
+	return i < len(m.Idempotent) && m.Idempotent[i] == path
+}
+
+// IsCanary reports whether the node is tagged canary.
+func (m *Metadata) IsCanary() bool { return m.Tags[MetaCanary] == "true" }
+
+// HasHandler reports whether the node serves handlers of version.
+func (m *Metadata) HasHandler(version string) bool {
+	for _, ver := range m.Handlers {
+		if ver == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMetadata decodes the metadata of a registry entry, the unknown keys are tags.
+// Entries of older nodes only hold "state=active".
+func ParseMetadata(s string) *Metadata {
+	v, _ := url.ParseQuery(s)
+	m := &Metadata{
+		State:     v.Get(MetaState),
+		Version:   v.Get(MetaVersion),
+		GitCommit: v.Get(MetaGitCommit),
+		BuildTime: v.Get(MetaBuildTime),
+		Mode:      v.Get(MetaMode),
+		Weight:    1,
+		Zone:      v.Get(MetaZone),
+		Group:     v.Get(MetaGroup),
+		Tags:      map[string]string{},
+	}
+	if w, err := strconv.Atoi(v.Get(MetaWeight)); err == nil {
+		m.Weight = w
+	}
+	if s := v.Get(MetaHandlers); s != "" {
+		m.Handlers = strings.Split(s, ",")
+	}
+	if s := v.Get(MetaIdempotent); s != "" {
+		m.Idempotent = strings.Split(s, ",")
+		sort.Strings(m.Idempotent)
+	}
+	if sec, err := strconv.ParseInt(v.Get(MetaStartTime), 10, 64); err == nil {
+		m.StartTime = time.Unix(sec, 0)
+	}
+	for k := range v {
+		switch k {
+		case MetaState, MetaVersion, MetaGitCommit, MetaBuildTime, MetaMode, MetaWeight, MetaZone, MetaGroup,
+			MetaHandlers, MetaIdempotent, MetaStartTime:
+		default:
+			m.Tags[k] = v.Get(k)
+		}
+	}
+	return m
+}
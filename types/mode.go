@@ -0,0 +1,37 @@
+package types
+
+import "github.com/ZYallers/rpcx-framework/consts"
+
+// ModeConfig is the behaviour profile of a run mode, the `global.modes.<mode>` config block.
+type ModeConfig struct {
+	FailMode    string          `mapstructure:"failMode" default:"failover" validate:"oneof=failover failfast failtry failbackup"`
+	SelectMode  string          `mapstructure:"selectMode" default:"roundrobin" validate:"oneof=random roundrobin weightedroundrobin weightedicmp consistenthash closest"`
+	AlertAtAll  bool            `mapstructure:"alertAtAll"`  // alerts asking for it @all the robot group
+	AlertAlways bool            `mapstructure:"alertAlways"` // Message.Always, alerts usually skipped are sent too
+	DebugReply  bool            `mapstructure:"debugReply"`  // the debug arg may add service details to replies
+	Namespace   NamespaceConfig `mapstructure:"namespace"`
+}
+
+// NamespaceConfig isolates the services of a host in their own discovery namespace, so a developer can run
+// some services locally and reach the others in the shared cluster of the mode.
+type NamespaceConfig struct {
+	Name     string `mapstructure:"name"`     // {hostname}, {mode} and {env:NAME} are expanded, no isolation when empty
+	Fallback bool   `mapstructure:"fallback"` // services not running in the namespace are discovered in the shared one
+}
+
+// DefaultModes are the profiles of the built-in modes, a mode defined in global.modes replaces its default as a whole.
+var DefaultModes = map[string]ModeConfig{
+	consts.DevelopMode: {FailMode: "failfast", SelectMode: "random", AlertAlways: true, DebugReply: true,
+		Namespace: NamespaceConfig{Name: "developer@{hostname}", Fallback: true}},
+	consts.GrayMode:    {FailMode: "failover", SelectMode: "roundrobin", DebugReply: true},
+	consts.ProduceMode: {FailMode: "failover", SelectMode: "roundrobin", AlertAtAll: true, DebugReply: true},
+}
+
+// Mode returns the profile of mode, the config ones first, false when it is defined nowhere.
+func (c *Config) Mode(mode string) (ModeConfig, bool) {
+	if m, ok := c.Global.Modes[mode]; ok {
+		return m, true
+	}
+	m, ok := DefaultModes[mode]
+	return m, ok
+}
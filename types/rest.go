@@ -2,11 +2,12 @@ package types
 
 type Restful map[string][]RestHandler
 type RestHandler struct {
-	Sort    int
-	Signed  bool
-	Logged  bool
-	Path    string
-	Version string
-	Method  string
-	Service IService
+	Sort       int
+	Signed     bool
+	Logged     bool
+	Idempotent bool // retried by the clients on the retryOn error classes, timeout and network by default, set by `idempotent:"on"`
+	Path       string
+	Version    string
+	Method     string
+	Service    IService
 }
@@ -10,10 +10,19 @@ import (
 	"github.com/soheilhy/cmux"
 )
 
+// Discovery is the registry of the run mode, see RegistryConfig.
 type Discovery struct {
+	Type           string
 	UpdateInterval time.Duration
 	BasePath       string
 	Addr           []string
+	Namespace      string // isolated namespace the base path belongs to, empty when shared
+	SharedBasePath string // base path of the shared namespace, set when the namespace falls back to it
+	Username       string
+	Password       string
+	Peers          map[string][]string
+	File           string
+	Register       bool
 }
 
 type Rpc struct {
@@ -25,10 +34,15 @@ type Rpc struct {
 	SystemIP           string
 	Addr               string
 	LogDir             string
+	Log                *LogConfig
+	Access             *AccessConfig
+	Debug              *DebugConfig
+	AdminToken         string
 	ErrorRobotToken    string
 	GracefulRobotToken string
 	SqlRobotToken      string
-	Etcd               *Discovery
+	Etcd               *Discovery // registry of any type, the name predates the other registries
+	Metadata           *Metadata  // published with every function registered
 	Server             *server.Server
 	SessionFunc        func() *redis.Client
 	Sender
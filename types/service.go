@@ -14,7 +14,6 @@ import (
 	"github.com/ZYallers/golib/funcs/php"
 	"github.com/ZYallers/golib/utils/json"
 	"github.com/smallnest/rpcx/server"
-	"github.com/spf13/viper"
 )
 
 type M map[string]interface{}
@@ -33,8 +32,9 @@ type Service struct {
 	reply   *interface{}
 }
 
-func (s *Service) getServiceConfig(key string) interface{} {
-	return viper.Get("service." + key)
+func (s *Service) debugReply() bool {
+	profile, _ := GetConfig().Mode(s.service.Env)
+	return profile.DebugReply
 }
 
 func (s *Service) Construct(sr interface{}, ctx context.Context, args map[string]interface{}, reply *interface{}) {
@@ -43,7 +43,7 @@ func (s *Service) Construct(sr interface{}, ctx context.Context, args map[string
 	s.args = args
 	s.reply = reply
 	rep := &Reply{}
-	if debug := s.GetString("debug"); debug == conv.ToString(s.getServiceConfig("debugValue")) {
+	if debug, value := s.GetString("debug"), GetConfig().Service.DebugValue; value != "" && debug == value && s.debugReply() {
 		s.debug = true
 		now := time.Now()
 		rep.Service = &ReplyService{
@@ -144,6 +144,7 @@ func (s *Service) Json(a ...interface{}) error {
 	if al > 2 {
 		rep.Data = a[2]
 	}
+	AccessFromContext(s.ctx).SetCode(rep.Code)
 	if s.debug {
 		now := time.Now()
 		rep.Service.End = &now
@@ -167,11 +168,12 @@ func (s *Service) WriteString(str string) error {
 }
 
 func (s *Service) SignCheck() bool {
-	sign := s.GetString(conv.ToString(s.getServiceConfig("signKey")))
+	c := GetConfig().Service
+	sign := s.GetString(c.SignKey)
 	if sign == "" {
 		return false
 	}
-	utime := s.GetString(conv.ToString(s.getServiceConfig("utimeKey")))
+	utime := s.GetString(c.TimeKey)
 	if utime == "" {
 		return false
 	}
@@ -179,11 +181,11 @@ func (s *Service) SignCheck() bool {
 	if err != nil || timestamp <= 0 {
 		return false
 	}
-	if time.Now().Unix()-timestamp > conv.ToInt64(s.getServiceConfig("signExpire")) {
+	if time.Now().Unix()-timestamp > c.SignExpire {
 		return false
 	}
 	hash := md5.New()
-	hash.Write([]byte(utime + conv.ToString(s.getServiceConfig("signSecret"))))
+	hash.Write([]byte(utime + c.SignSecret))
 	realSign := base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(hash.Sum(nil))))
 	if sign == realSign {
 		return true
@@ -197,7 +199,7 @@ func (s *Service) LoggedUserData(key ...string) map[string]interface{} {
 	case 1:
 		token = key[0]
 	default:
-		token = s.GetString(conv.ToString(s.getServiceConfig("tokenKey")))
+		token = s.GetString(GetConfig().Service.TokenKey)
 	}
 	if s.service.SessionFunc == nil {
 		return nil
@@ -206,7 +208,7 @@ func (s *Service) LoggedUserData(key ...string) map[string]interface{} {
 	if session == nil {
 		return nil
 	}
-	if str, _ := session.Get(conv.ToString(s.getServiceConfig("sessionKeyPrefix")) + token).Result(); str != "" {
+	if str, _ := session.Get(GetConfig().Service.SessionKeyPrefix + token).Result(); str != "" {
 		return php.Unserialize(str)
 	}
 	return nil